@@ -0,0 +1,90 @@
+package accessTokenCache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// EventSource can't send Authorization header, so event streams are opened
+// with a short-lived ticket in the url instead of the access token. Ticket
+// keeps one stream open at a time and stays valid for a short while after
+// the stream is closed, which is enough for EventSource to reconnect.
+const STREAM_TICKET_LIVE_DURATION = 30 * time.Second
+const STREAM_TICKET_RECONNECT_DURATION = 30 * time.Second
+
+const StreamTicketInvalidMsg = "invalid_stream_ticket"
+
+type streamTicket struct {
+	token     string
+	expiresAt time.Time
+	// cancels the open stream, nil when there is none
+	cancel     context.CancelFunc
+	connection int
+}
+
+type StreamTicketCache struct {
+	tickets map[string]*streamTicket
+	mu      sync.Mutex
+}
+
+func NewStreamTicketCache() *StreamTicketCache {
+	return &StreamTicketCache{
+		tickets: make(map[string]*streamTicket),
+	}
+}
+
+func (cache *StreamTicketCache) Create(token string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	now := time.Now()
+	for key, _ticket := range cache.tickets {
+		if _ticket.cancel == nil && _ticket.expiresAt.Before(now) {
+			delete(cache.tickets, key)
+		}
+	}
+	cache.tickets[ticket] = &streamTicket{
+		token:     token,
+		expiresAt: now.Add(STREAM_TICKET_LIVE_DURATION),
+	}
+	return ticket, nil
+}
+
+// Returns access token of the ticket and release to be called once the
+// stream is closed. Reconnect may come before the dropped stream is noticed,
+// so opening a stream cancels the one still open with the same ticket.
+func (cache *StreamTicketCache) Redeem(ticket string, cancel context.CancelFunc) (string, func(), error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	_ticket, exists := cache.tickets[ticket]
+	if !exists || (_ticket.cancel == nil && _ticket.expiresAt.Before(time.Now())) {
+		return "", nil, errors.New(StreamTicketInvalidMsg)
+	}
+	if _ticket.cancel != nil {
+		_ticket.cancel()
+	}
+	_ticket.cancel = cancel
+	_ticket.connection++
+	connection := _ticket.connection
+
+	release := func() {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		if _ticket.connection == connection {
+			_ticket.cancel = nil
+			_ticket.expiresAt = time.Now().Add(STREAM_TICKET_RECONNECT_DURATION)
+		}
+	}
+	return _ticket.token, release, nil
+}
+
+var STREAM_TICKET_CACHE = NewStreamTicketCache()
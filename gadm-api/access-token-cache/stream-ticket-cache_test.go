@@ -0,0 +1,53 @@
+package accessTokenCache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStreamTicketCache(t *testing.T) {
+	t.Logf("Test: StreamTicketCache - one open stream, reconnect and expiration")
+
+	cache := NewStreamTicketCache()
+	ticket, err := cache.Create("token")
+	if err != nil {
+		t.Fatalf("failed to create ticket: %v", err)
+	}
+
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	token, releaseFirst, err := cache.Redeem(ticket, firstCancel)
+	if err != nil || token != "token" {
+		t.Fatalf("expected token of ticket, got %s %v", token, err)
+	}
+
+	// reconnect before the first stream is noticed to be dropped
+	_, secondCancel := context.WithCancel(context.Background())
+	token, releaseSecond, err := cache.Redeem(ticket, secondCancel)
+	if err != nil || token != "token" {
+		t.Fatalf("expected reconnect with open ticket, got %s %v", token, err)
+	}
+	if firstCtx.Err() == nil {
+		t.Errorf("expected first stream to be canceled")
+	}
+	releaseFirst()
+	if cache.tickets[ticket].cancel == nil {
+		t.Errorf("expected release of canceled stream to keep the second one open")
+	}
+
+	releaseSecond()
+	cache.tickets[ticket].expiresAt = time.Now().Add(-time.Second)
+	if _, _, err := cache.Redeem(ticket, func() {}); err == nil || err.Error() != StreamTicketInvalidMsg {
+		t.Errorf("expected expired ticket to be invalid, got %v", err)
+	}
+	if _, _, err := cache.Redeem("unknown", func() {}); err == nil || err.Error() != StreamTicketInvalidMsg {
+		t.Errorf("expected unknown ticket to be invalid, got %v", err)
+	}
+
+	if _, err := cache.Create("other-token"); err != nil {
+		t.Fatalf("failed to create ticket: %v", err)
+	}
+	if _, exists := cache.tickets[ticket]; exists {
+		t.Errorf("expected expired ticket to be removed")
+	}
+}
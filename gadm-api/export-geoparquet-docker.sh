@@ -0,0 +1,12 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+cd "${SCRIPT_DIR}/.."
+
+exec docker compose run -d --build \
+  -e SERVICE_TYPE=cron_job \
+  -e CRON_JOB_NAME=export_geoparquet \
+  -e GEOPARQUET_OUTPUT_DIR=/app/public \
+  -v ./file-server/public:/app/public \
+  gadm-api | tee export-geoparquet-detached.log
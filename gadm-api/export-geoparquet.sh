@@ -0,0 +1,5 @@
+
+export CRON_JOB_NAME="export_geoparquet"
+export SERVICE_TYPE="cron_job"
+
+cd main && go run .
@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gadm-api/infra/pg"
+	"gadm-api/logger"
+	"gadm-api/models/adm"
+	"gadm-api/utils"
+)
+
+// relative to gadm-api/main where jobs are run locally
+const DEFAULT_GEOPARQUET_OUTPUT_DIR = "../../file-server/public"
+const GEOPARQUET_MAX_LV = 5
+
+// Writes adm_lv<N>.parquet for every level into the file server directory.
+func ExportGeoparquetJob() {
+	dbPool := pg.InitPgPool(MAX_PG_CONNS)
+	defer dbPool.Close()
+
+	outputDir := os.Getenv("GEOPARQUET_OUTPUT_DIR")
+	if outputDir == "" {
+		outputDir = DEFAULT_GEOPARQUET_OUTPUT_DIR
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		logger.Fatal("failed_to_create_output_dir %s %v", outputDir, err)
+	}
+
+	logger.Info("export_geoparquet_job started output_dir=%s", outputDir)
+
+	admRepo := adm.NewAdmRepo(dbPool)
+	admService := adm.NewAdmService(admRepo)
+	ctx := context.Background()
+	for lv := 0; lv <= GEOPARQUET_MAX_LV; lv++ {
+		filePath := filepath.Join(outputDir, fmt.Sprintf("adm_lv%d.parquet", lv))
+		err := utils.WriteFileAtomically(filePath, func(w io.Writer) error {
+			return admService.WriteAdmsGeoparquet(ctx, w, lv)
+		})
+		if err != nil {
+			logger.Fatal("failed_to_export_geoparquet lv=%d %v", lv, err)
+		}
+		logger.Info("export_geoparquet_progress lv=%d path=%s", lv, filePath)
+	}
+}
@@ -34,3 +34,17 @@ func PopulateAdmNeighborsJob() {
 		logger.Fatal("failed_to_populate_adm_neighbors %v", err)
 	}
 }
+
+func PopulateAdmNamesJob() {
+	dbPool := pg.InitPgPool(MAX_PG_CONNS)
+	defer dbPool.Close()
+
+	logger.Info("populate_adm_names_job started")
+
+	admRepo := adm.NewAdmRepo(dbPool)
+	admService := adm.NewAdmService(admRepo)
+	err := admService.PopulateAdmNames(context.Background())
+	if err != nil {
+		logger.Fatal("failed_to_populate_adm_names %v", err)
+	}
+}
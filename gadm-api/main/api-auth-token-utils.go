@@ -8,6 +8,16 @@ import (
 )
 
 const NOT_RESULTS_FOR_QUERY_PG_MSG = "no rows in result set"
+const SSE_TICKET_QUERY_PARAM = "ticket"
+
+// EventSource can't set headers, event streams are opened with a stream
+// ticket instead of the token, see createSseTicketHandler.
+func getSseTicketFromRequest(r *http.Request) string {
+	if r.Header.Get("Authorization") != "" || !strings.HasSuffix(r.URL.Path, "/geojsonl/sse") {
+		return ""
+	}
+	return r.URL.Query().Get(SSE_TICKET_QUERY_PARAM)
+}
 
 func getApiAuthTokenFromRequest(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
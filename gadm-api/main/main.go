@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
+	"time"
 
 	gameloop "gadm-api/game-loop"
 	"gadm-api/infra/pg"
@@ -13,6 +17,7 @@ import (
 	"gadm-api/logger"
 	"gadm-api/models/access_token"
 	"gadm-api/models/adm"
+	"gadm-api/models/export_job"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -34,8 +39,12 @@ func main() {
 		switch jobName {
 		case "populate_adm_tree":
 			jobs.PopulateAdmTreeJob()
+		case "populate_adm_names":
+			jobs.PopulateAdmNamesJob()
 		case "populate_adm_neighbors":
 			jobs.PopulateAdmNeighborsJob()
+		case "export_geoparquet":
+			jobs.ExportGeoparquetJob()
 		default:
 			logger.Fatal("unknown_cron_job_name %s", jobName)
 		}
@@ -47,6 +56,35 @@ func main() {
 }
 
 const MAX_PG_CONNS = int32(45)
+const DEFAULT_REVERSE_GEOCODE_BATCH_MAX_POINTS = 1000
+const DEFAULT_DOCS_URL = "https://docs.worldlines.dev/"
+const DEFAULT_EXPORTS_DIR = "exports"
+
+func getIntFromEnv(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	_value, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warning("invalid_int_env_variable %s=%s using_default=%d", name, value, defaultValue)
+		return defaultValue
+	}
+	return _value
+}
+
+// Secret signing pagination cursors, has to be shared by all api instances.
+func getCursorSecret() []byte {
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	logger.Warning("cursor_secret_not_set cursors_are_invalidated_on_restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		logger.Fatal("failed_to_generate_cursor_secret %v", err)
+	}
+	return secret
+}
 
 func startRestApi() {
 	dbPool := pg.InitPgPool(MAX_PG_CONNS)
@@ -83,14 +121,165 @@ func getApiHandlers(dbPool *pgxpool.Pool, baseApiPath string) http.Handler {
 	admHandler := adm.NewAdmNeighborsHandler(admService)
 	mux.HandleFunc("/adm-neighbors", admHandler.AdmNeighborsHandler)
 	mux.HandleFunc("/reverse-geocode", admHandler.AdmForLatLngHandler)
-	mux.HandleFunc("/geojsonl", admHandler.AdmGeojsonlHandler)
+	geojsonlPath := "/geojsonl"
+	geojsonlBaseUrl := url.URL{Path: path.Join(baseApiPath, geojsonlPath)}
+	mux.HandleFunc(
+		geojsonlPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmGeojsonlHandler(w, r, geojsonlBaseUrl)
+		},
+	)
+	geojsonlSseBaseUrl := url.URL{Path: path.Join(baseApiPath, geojsonlPath, "sse")}
+	mux.HandleFunc(
+		"GET "+geojsonlPath+"/sse",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmGeojsonlSseHandler(w, r, geojsonlSseBaseUrl)
+		},
+	)
+	mux.HandleFunc("POST "+geojsonlPath+"/sse/ticket", createSseTicketHandler)
+	mux.HandleFunc("/fgb", admHandler.AdmFlatgeobufHandler)
+	mux.HandleFunc("GET /adm/{id}", admHandler.AdmByIdHandler)
+	mux.HandleFunc("/adm-lookup", admHandler.AdmLookupHandler)
+	mux.HandleFunc("/search", admHandler.SearchAdmsHandler)
+	mux.HandleFunc(
+		"GET /adm/{id}/parent",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmParentHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc(
+		"GET /adm/{id}/children",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmChildrenHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc(
+		"GET /adm/{id}/ancestors",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmAncestorsHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc(
+		"GET /adm/{id}/descendants",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmDescendantsHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc("GET /adm/{id}/topojson", admHandler.AdmTopojsonHandler)
+	mux.HandleFunc("GET /adm/{id}/stats", admHandler.AdmStatsHandler)
+	mux.HandleFunc("GET /adm/{id}/border/{neighbor_id}", admHandler.AdmBorderHandler)
+	mux.HandleFunc("GET /adm/{id}/neighborhood", admHandler.AdmNeighborhoodHandler)
+	mux.HandleFunc("GET /adm/{id}/path/{target_id}", admHandler.AdmPathHandler)
 
+	batchReverseGeocodeMaxPoints := getIntFromEnv(
+		"REVERSE_GEOCODE_BATCH_MAX_POINTS",
+		DEFAULT_REVERSE_GEOCODE_BATCH_MAX_POINTS,
+	)
+	mux.HandleFunc(
+		"/batch-reverse-geocode",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.BatchReverseGeocodeHandler(w, r, batchReverseGeocodeMaxPoints)
+		},
+	)
+
+	cursorCodec := adm.NewCursorCodec(getCursorSecret())
 	fcPath := "/fc"
 	fcBaseUrl := url.URL{Path: path.Join(baseApiPath, fcPath)}
 	mux.HandleFunc(
 		fcPath,
 		func(w http.ResponseWriter, r *http.Request) {
-			admHandler.GetAdmFeatureCollectionHandler(w, r, fcBaseUrl)
+			admHandler.GetAdmFeatureCollectionHandler(w, r, fcBaseUrl, cursorCodec)
+		},
+	)
+
+	tileZoomRanges := adm.DefaultTileLevelZoomRanges
+	if value := os.Getenv("TILE_LEVEL_ZOOM_RANGES"); value != "" {
+		ranges, err := adm.ParseTileLevelZoomRanges(value)
+		if err != nil {
+			logger.Fatal("invalid_tile_level_zoom_ranges %v", err)
+		}
+		tileZoomRanges = ranges
+	}
+	mux.HandleFunc(
+		"GET /tiles/{z}/{x}/{y}",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmTileHandler(w, r, tileZoomRanges)
+		},
+	)
+
+	admsForGeometryPath := "/adms-for-geometry"
+	admsForGeometryBaseUrl := url.URL{Path: path.Join(baseApiPath, admsForGeometryPath)}
+	mux.HandleFunc(
+		admsForGeometryPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.AdmsForGeometryHandler(w, r, admsForGeometryBaseUrl)
+		},
+	)
+
+	exportsDir := os.Getenv("EXPORTS_DIR")
+	if exportsDir == "" {
+		exportsDir = DEFAULT_EXPORTS_DIR
+	}
+	exportJobRepo := export_job.NewExportJobRepo(dbPool)
+	exportJobService := export_job.NewExportJobService(exportJobRepo, admService, export_job.ExportJobConfig{
+		OutputDir: exportsDir,
+		Workers:   getIntFromEnv("EXPORT_WORKERS", export_job.DEFAULT_EXPORT_WORKERS),
+		Retention: time.Duration(getIntFromEnv(
+			"EXPORT_RETENTION_HOURS",
+			int(export_job.DEFAULT_EXPORT_RETENTION.Hours()),
+		)) * time.Hour,
+	})
+	if err := exportJobService.StartWorkers(context.Background()); err != nil {
+		logger.Fatal("failed_to_start_export_job_workers %v", err)
+	}
+	exportJobHandler := export_job.NewExportJobHandler(exportJobService)
+	mux.HandleFunc(
+		"POST /exports",
+		func(w http.ResponseWriter, r *http.Request) {
+			exportJobHandler.CreateExportJobHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc(
+		"GET /exports/{id}",
+		func(w http.ResponseWriter, r *http.Request) {
+			exportJobHandler.GetExportJobHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc("GET /exports/{id}/download", exportJobHandler.DownloadExportHandler)
+
+	docsUrl := os.Getenv("DOCS_URL")
+	if docsUrl == "" {
+		docsUrl = DEFAULT_DOCS_URL
+	}
+	mux.HandleFunc(
+		"GET /ogc",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.OgcLandingPageHandler(w, r, baseApiPath, docsUrl)
+		},
+	)
+	mux.HandleFunc("GET /ogc/conformance", admHandler.OgcConformanceHandler)
+	mux.HandleFunc(
+		"GET /ogc/collections",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.OgcCollectionsHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc(
+		"GET /ogc/collections/{collectionId}",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.OgcCollectionHandler(w, r, baseApiPath)
+		},
+	)
+	mux.HandleFunc(
+		"GET /ogc/collections/{collectionId}/items",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.OgcItemsHandler(w, r, baseApiPath, cursorCodec)
+		},
+	)
+	mux.HandleFunc(
+		"GET /ogc/collections/{collectionId}/items/{featureId}",
+		func(w http.ResponseWriter, r *http.Request) {
+			admHandler.OgcItemHandler(w, r, baseApiPath)
 		},
 	)
 
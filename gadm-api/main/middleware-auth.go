@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -14,7 +15,21 @@ import (
 func GetAuthMiddleWare(pgPool *pgxpool.Pool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token, err := getApiAuthTokenFromRequest(r)
+			var token string
+			var err error
+			if ticket := getSseTicketFromRequest(r); ticket != "" {
+				// reconnect with the same ticket cancels the stream through ctx
+				ctx, cancel := context.WithCancel(r.Context())
+				defer cancel()
+				var release func()
+				token, release, err = accessTokenCache.STREAM_TICKET_CACHE.Redeem(ticket, cancel)
+				if err == nil {
+					defer release()
+					r = r.WithContext(ctx)
+				}
+			} else {
+				token, err = getApiAuthTokenFromRequest(r)
+			}
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
@@ -11,8 +11,12 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		queryParams := r.URL.Query()
+		if queryParams.Has(SSE_TICKET_QUERY_PARAM) {
+			queryParams.Set(SSE_TICKET_QUERY_PARAM, "redacted")
+		}
 		logger.Info("request_started method=%s path=%s remote_addr=%s query_params=%v",
-			r.Method, r.URL.Path, r.RemoteAddr, r.URL.Query())
+			r.Method, r.URL.Path, r.RemoteAddr, queryParams)
 
 		next.ServeHTTP(w, r)
 
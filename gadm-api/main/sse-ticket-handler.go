@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	accessTokenCache "gadm-api/access-token-cache"
+	"gadm-api/logger"
+)
+
+// Issues a ticket opening /geojsonl/sse, request itself is authorized with
+// Authorization header like other endpoints.
+func createSseTicketHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := getApiAuthTokenFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ticket, err := accessTokenCache.STREAM_TICKET_CACHE.Create(token)
+	if err != nil {
+		logger.Error("failed_to_create_sse_ticket %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"ticket":     ticket,
+		"expires_in": int(accessTokenCache.STREAM_TICKET_LIVE_DURATION.Seconds()),
+	})
+}
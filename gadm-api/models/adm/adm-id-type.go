@@ -0,0 +1,54 @@
+package adm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type admIdType string
+
+const (
+	// internal uuid of gadm.adm row
+	admIdTypeId admIdType = "id"
+	// GADM GID, e.g. POL.14.3_1
+	admIdTypeGid admIdType = "gid"
+	// ISO 3166-1 alpha-3 country code or ISO 3166-2 subdivision code
+	admIdTypeIso admIdType = "iso"
+	// HASC code stored by GADM for levels 1-3, e.g. PL.MZ
+	admIdTypeHasc admIdType = "hasc"
+)
+
+func getAdmIdTypeFromString(idType string) (admIdType, error) {
+	switch admIdType(idType) {
+	case "":
+		return admIdTypeGid, nil
+	case admIdTypeId, admIdTypeGid, admIdTypeIso, admIdTypeHasc:
+		return admIdType(idType), nil
+	default:
+		return "", fmt.Errorf("invalid_adm_id_type: %s", idType)
+	}
+}
+
+// Returns identifier in the form it is stored in the database or false if
+// it can never match any adm, e.g. malformed uuid.
+func (idType admIdType) normalize(id string) (string, bool) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", false
+	}
+
+	switch idType {
+	case admIdTypeId:
+		_id, err := uuid.Parse(id)
+		if err != nil {
+			return "", false
+		}
+		return _id.String(), true
+	case admIdTypeIso, admIdTypeHasc:
+		return strings.ToUpper(id), true
+	default:
+		return id, true
+	}
+}
@@ -0,0 +1,110 @@
+package adm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const MAX_PROJECTED_FIELDS = 100
+
+type admGeometry string
+
+const (
+	admGeometryNone     admGeometry = "none"
+	admGeometryBbox     admGeometry = "bbox"
+	admGeometryCentroid admGeometry = "centroid"
+	admGeometryFull     admGeometry = "full"
+)
+
+func getAdmGeometryFromString(value string, defaultGeometry admGeometry) (admGeometry, error) {
+	switch admGeometry(value) {
+	case "":
+		return defaultGeometry, nil
+	case admGeometryNone, admGeometryBbox, admGeometryCentroid, admGeometryFull:
+		return admGeometry(value), nil
+	default:
+		return "", fmt.Errorf("invalid_geometry: %s", value)
+	}
+}
+
+func (geometry admGeometry) needsGeometryTable() bool {
+	return geometry == admGeometryBbox || geometry == admGeometryCentroid || geometry == admGeometryFull
+}
+
+// Selects which parts of adm are queried. Zero value selects whole
+// metadata without geometry.
+type admProjection struct {
+	// metadata keys, nil selects whole metadata
+	fields   []string
+	geometry admGeometry
+}
+
+var metadataKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Keys are embedded into SQL so only plain identifiers are accepted.
+func getMetadataKeysFromString(value string, maxKeys int) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	keys := []string{}
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if !metadataKeyRegexp.MatchString(key) {
+			return nil, fmt.Errorf("invalid_metadata_key: %s", key)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) > maxKeys {
+		return nil, fmt.Errorf("metadata_keys_limit_exceeded: %d", len(keys))
+	}
+	return keys, nil
+}
+
+// Metadata column, restricted to projected keys. id, lv and geom_hash are
+// columns of adm and are always returned.
+func (projection admProjection) metadataSqlField() string {
+	if projection.fields == nil {
+		return "adm.metadata"
+	}
+
+	args := []string{}
+	for _, key := range projection.fields {
+		switch key {
+		case "id", "lv", "geom_hash":
+			continue
+		}
+		args = append(args, fmt.Sprintf("'%s', adm.metadata -> '%s'", key, key))
+	}
+	if len(args) == 0 {
+		return "'{}'::jsonb AS metadata"
+	}
+	return fmt.Sprintf("jsonb_strip_nulls(jsonb_build_object(%s)) AS metadata", strings.Join(args, ", "))
+}
+
+func (projection admProjection) admSqlFields() []string {
+	return []string{projection.metadataSqlField(), "adm.id", "adm.lv", "adm.geom_hash"}
+}
+
+// Geometry columns computed from geom expression, gadm.adm_geometries has
+// to be joined as g when the projection includes geometry.
+func (projection admProjection) geometrySqlFields(geom string, bboxSqlField string, precision int) []string {
+	switch projection.geometry {
+	case admGeometryBbox:
+		return []string{bboxSqlField}
+	case admGeometryCentroid:
+		return []string{fmt.Sprintf("ST_AsGeoJSON(ST_Centroid(%s), %d) as geom", geom, precision)}
+	case admGeometryFull:
+		return []string{fmt.Sprintf("ST_AsGeoJSON(%s, %d) as geom", geom, precision), bboxSqlField}
+	default:
+		return nil
+	}
+}
+
+// Projection fields of adm in EPSG:4326 with default precision.
+func (projection admProjection) sqlFields() []string {
+	return append(
+		projection.admSqlFields(),
+		projection.geometrySqlFields("g.geom", admBboxSqlField, DEFAULT_COORDINATE_PRECISION)...,
+	)
+}
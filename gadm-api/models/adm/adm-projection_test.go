@@ -0,0 +1,78 @@
+package adm
+
+import (
+	"testing"
+)
+
+func TestAdmProjectionMetadataSqlField(t *testing.T) {
+	t.Logf("Test: admProjection.metadataSqlField - selects only projected keys")
+
+	cases := []struct {
+		fields   []string
+		expected string
+	}{
+		{nil, "adm.metadata"},
+		{[]string{}, "'{}'::jsonb AS metadata"},
+		{[]string{"id", "lv"}, "'{}'::jsonb AS metadata"},
+		{
+			[]string{"name_1", "geom_hash", "gid_1"},
+			"jsonb_strip_nulls(jsonb_build_object('name_1', adm.metadata -> 'name_1', 'gid_1', adm.metadata -> 'gid_1')) AS metadata",
+		},
+	}
+	for _, c := range cases {
+		field := admProjection{fields: c.fields}.metadataSqlField()
+		if field != c.expected {
+			t.Errorf("unexpected field for %v: %s. Expected %s", c.fields, field, c.expected)
+		}
+	}
+}
+
+func TestAdmProjectionGeometrySqlFields(t *testing.T) {
+	t.Logf("Test: admProjection.sqlFields - geometry columns per geometry mode")
+
+	expectedCounts := map[admGeometry]int{
+		admGeometryNone:     4,
+		admGeometryBbox:     5,
+		admGeometryCentroid: 5,
+		admGeometryFull:     6,
+	}
+	for geometry, expectedCount := range expectedCounts {
+		fields := admProjection{geometry: geometry}.sqlFields()
+		if len(fields) != expectedCount {
+			t.Errorf("unexpected fields for %s: %v. Expected %d fields", geometry, fields, expectedCount)
+		}
+	}
+}
+
+func TestGetAdmGeometryFromString(t *testing.T) {
+	t.Logf("Test: getAdmGeometryFromString - default and invalid values")
+
+	geometry, err := getAdmGeometryFromString("", admGeometryFull)
+	if err != nil || geometry != admGeometryFull {
+		t.Errorf("unexpected geometry: %s, %v. Expected %s", geometry, err, admGeometryFull)
+	}
+
+	geometry, err = getAdmGeometryFromString("centroid", admGeometryFull)
+	if err != nil || geometry != admGeometryCentroid {
+		t.Errorf("unexpected geometry: %s, %v. Expected %s", geometry, err, admGeometryCentroid)
+	}
+
+	if _, err := getAdmGeometryFromString("wkt", admGeometryFull); err == nil {
+		t.Errorf("expected error for invalid geometry")
+	}
+}
+
+func TestGetMetadataKeysFromString(t *testing.T) {
+	t.Logf("Test: getMetadataKeysFromString - rejects keys unsafe for SQL")
+
+	keys, err := getMetadataKeysFromString("name_1, gid_1", MAX_PROJECTED_FIELDS)
+	if err != nil || len(keys) != 2 || keys[0] != "name_1" || keys[1] != "gid_1" {
+		t.Errorf("unexpected keys: %v, %v", keys, err)
+	}
+
+	for _, value := range []string{"name_1,", "name'1", "a b"} {
+		if _, err := getMetadataKeysFromString(value, MAX_PROJECTED_FIELDS); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
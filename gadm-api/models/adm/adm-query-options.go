@@ -1,24 +1,56 @@
 package adm
 
-import "gadm-api/utils"
+import (
+	"fmt"
+	"gadm-api/utils"
+	"strconv"
+)
 
 type admQueryOpts struct {
-	lv              *int
-	startAfterFid   *string
-	startAfterId    *string
-	batchSize       *int
-	includeGeometry bool
+	lv            *int
+	startAfterFid *string
+	startAfterId  *string
+	batchSize     *int
+	projection    admProjection
+	precision     int
+	crs           crs
+	bbox          *bbox
+	country       *string
+	parentId      *string
+	id            *string
+	csvGeometry   csvGeometry
+	includeArea   bool
+	sort          admSort
+	keyset        *admKeyset
 }
 
+// minx, miny, maxx, maxy in CRS84, minx > maxx means bbox crosses antimeridian
+type bbox [4]float64
+
+func (b bbox) String() string {
+	return fmt.Sprintf(
+		"%s,%s,%s,%s",
+		strconv.FormatFloat(b[0], 'f', -1, 64),
+		strconv.FormatFloat(b[1], 'f', -1, 64),
+		strconv.FormatFloat(b[2], 'f', -1, 64),
+		strconv.FormatFloat(b[3], 'f', -1, 64),
+	)
+}
+
+const DEFAULT_BATCH_SIZE = 100
+const DEFAULT_COORDINATE_PRECISION = 6
+const MAX_COORDINATE_PRECISION = 15
+
 type admQueryOptsBuilder struct {
 	conf admQueryOpts
 }
 
 func NewAdmQueryOptsBuilder() *admQueryOptsBuilder {
-	batchSize := 100
+	batchSize := DEFAULT_BATCH_SIZE
 	return &admQueryOptsBuilder{conf: admQueryOpts{
-		batchSize:       &batchSize,
-		includeGeometry: false,
+		batchSize: &batchSize,
+		precision: DEFAULT_COORDINATE_PRECISION,
+		crs:       defaultCrs,
 	}}
 }
 
@@ -39,21 +71,23 @@ func (builder *admQueryOptsBuilder) SetStartAfterFid(startAfterFid string) *admQ
 	return builder
 }
 
+// Geometries of lower levels are heavier, so fewer of them fit in a page.
+func getMaxBatchSize(lv *int) int {
+	if lv == nil {
+		return DEFAULT_BATCH_SIZE
+	} else if *lv < 2 {
+		return 5
+	} else if *lv < 4 {
+		return 20
+	}
+	return 50
+}
+
 func (builder *admQueryOptsBuilder) SetLvAndBatchSize(lv *int, batchSize *int) *admQueryOptsBuilder {
 	builder.conf.lv = lv
 
-	if lv != nil && batchSize != nil {
-		var _batchSize int
-		if *lv < 2 {
-			_batchSize = utils.Clamp(*batchSize, 1, 5)
-		} else if *lv < 4 {
-			_batchSize = utils.Clamp(*batchSize, 1, 20)
-		} else {
-			_batchSize = utils.Clamp(*batchSize, 1, 50)
-		}
-		builder.conf.batchSize = &_batchSize
-	} else if batchSize != nil {
-		v := utils.Clamp(*batchSize, 1, 100)
+	if batchSize != nil {
+		v := utils.Clamp(*batchSize, 1, getMaxBatchSize(lv))
 		builder.conf.batchSize = &v
 	} else {
 		builder.conf.batchSize = nil
@@ -62,11 +96,67 @@ func (builder *admQueryOptsBuilder) SetLvAndBatchSize(lv *int, batchSize *int) *
 	return builder
 }
 
+func (opts admQueryOpts) hasCsvGeometry() bool {
+	return opts.csvGeometry != "" && opts.csvGeometry != csvGeometryNone
+}
+
+// Whether the options limit result to a reasonably small set of adms.
+func (opts admQueryOpts) isBounded() bool {
+	return opts.batchSize != nil ||
+		opts.bbox != nil ||
+		opts.country != nil ||
+		opts.parentId != nil ||
+		opts.id != nil ||
+		(opts.lv != nil && *opts.lv <= 1)
+}
+
 func (builder *admQueryOptsBuilder) Build() (admQueryOpts, error) {
 	return builder.conf, nil
 }
 
-func (builder *admQueryOptsBuilder) SetIncludeGeometry(includeGeometry bool) *admQueryOptsBuilder {
-	builder.conf.includeGeometry = includeGeometry
+func (builder *admQueryOptsBuilder) SetProjection(projection admProjection) *admQueryOptsBuilder {
+	builder.conf.projection = projection
+	return builder
+}
+
+func (builder *admQueryOptsBuilder) SetPrecision(precision *int) *admQueryOptsBuilder {
+	if precision == nil {
+		return builder
+	}
+	builder.conf.precision = utils.Clamp(*precision, 0, MAX_COORDINATE_PRECISION)
+	return builder
+}
+
+func (builder *admQueryOptsBuilder) SetCrs(crs crs) *admQueryOptsBuilder {
+	builder.conf.crs = crs
+	return builder
+}
+
+func (builder *admQueryOptsBuilder) SetBbox(bbox *bbox) *admQueryOptsBuilder {
+	builder.conf.bbox = bbox
+	return builder
+}
+
+func (builder *admQueryOptsBuilder) SetCountry(country string) *admQueryOptsBuilder {
+	if country == "" {
+		return builder
+	}
+	builder.conf.country = &country
+	return builder
+}
+
+func (builder *admQueryOptsBuilder) SetParentId(parentId string) *admQueryOptsBuilder {
+	if parentId == "" {
+		return builder
+	}
+	builder.conf.parentId = &parentId
+	return builder
+}
+
+func (builder *admQueryOptsBuilder) SetId(id string) *admQueryOptsBuilder {
+	if id == "" {
+		return builder
+	}
+	builder.conf.id = &id
 	return builder
 }
@@ -0,0 +1,29 @@
+package adm
+
+type admRelation string
+
+const (
+	admRelationParent      admRelation = "parent"
+	admRelationChildren    admRelation = "children"
+	admRelationAncestors   admRelation = "ancestors"
+	admRelationDescendants admRelation = "descendants"
+)
+
+// GADM has 6 levels (0-5) so no adm is more than 5 edges away from
+// its farthest ancestor or descendant.
+const MAX_ADM_TREE_DEPTH = 5
+
+func (relation admRelation) isUpwards() bool {
+	return relation == admRelationParent || relation == admRelationAncestors
+}
+
+func (relation admRelation) maxDepth(depth *int) int {
+	switch relation {
+	case admRelationParent, admRelationChildren:
+		return 1
+	}
+	if depth == nil {
+		return MAX_ADM_TREE_DEPTH
+	}
+	return *depth
+}
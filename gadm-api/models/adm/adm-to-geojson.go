@@ -22,15 +22,15 @@ func convertAdmsToFeatureCollection(adms []Adm) (*geojson.FeatureCollection, err
 	return fc, nil
 }
 
+// Adms queried without geometry are converted to features with null geometry.
 func convertAdmsToGeojson(adm Adm) (*geojson.Feature, error) {
-	if len(adm.Geom) == 0 {
-		logger.Warning("missing_geometry_for_adm_fc_conversion: adm_id=%s", adm.ID)
-		return nil, fmt.Errorf("missing_geometry_for_adm_fc_conversion: adm_id=%s", adm.ID)
-	}
-
-	geom, err := geojson.UnmarshalGeometry(adm.Geom)
-	if err != nil {
-		return nil, fmt.Errorf("failed_to_unmarshal_geometry: adm_id=%s: %w", adm.ID, err)
+	var geom *geojson.Geometry
+	if len(adm.Geom) > 0 {
+		var err error
+		geom, err = geojson.UnmarshalGeometry(adm.Geom)
+		if err != nil {
+			return nil, fmt.Errorf("failed_to_unmarshal_geometry: adm_id=%s: %w", adm.ID, err)
+		}
 	}
 
 	feature := geojson.NewFeature(geom)
@@ -44,5 +44,11 @@ func convertAdmsToGeojson(adm Adm) (*geojson.Feature, error) {
 	}
 	feature.SetProperty("lv", adm.Level)
 	feature.SetProperty("geom_hash", adm.GeomHash)
+
+	if adm.OverlapAreaSqM != nil {
+		feature.SetProperty("overlap_area_sq_m", *adm.OverlapAreaSqM)
+		feature.SetProperty("overlap_fraction_of_input", adm.OverlapFractionOfInput)
+		feature.SetProperty("overlap_fraction_of_adm", adm.OverlapFractionOfAdm)
+	}
 	return feature, nil
 }
@@ -0,0 +1,22 @@
+package adm
+
+import "encoding/json"
+
+const (
+	// shares at least one boundary segment
+	admContactBorder = "border"
+	// boundaries only meet at points, like corners of a grid
+	admContactPoint = "point"
+	admContactNone  = "none"
+)
+
+// Shared boundary of two adms, length is geodesic. Geometry is a
+// (Multi)LineString for border contact, (Multi)Point for point contact and
+// null when adms don't touch.
+type admBorder struct {
+	ID         string          `db:"id" json:"id"`
+	NeighborID string          `db:"neighbor_id" json:"neighbor_id"`
+	Contact    string          `db:"contact" json:"contact"`
+	LengthM    float64         `db:"length_m" json:"length_m"`
+	Geometry   json.RawMessage `db:"geometry" json:"geometry"`
+}
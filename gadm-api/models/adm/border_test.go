@@ -0,0 +1,54 @@
+package adm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetAdmBorderSqlQuery(t *testing.T) {
+	t.Logf("Test: getAdmBorderSqlQuery - boundary intersection split into lines and points")
+
+	admId := "0f6d4c1e-7c9b-4e0a-9a43-5d1c2b0d8e11"
+	neighborId := "5b1c2d3e-4f50-4a6b-8c7d-9e0f1a2b3c4d"
+	sql, args, err := getAdmBorderSqlQuery(admId, neighborId)
+	if err != nil {
+		t.Fatalf("failed to build query: %v", err)
+	}
+
+	for _, expected := range []string{
+		"ST_Intersection(ST_Boundary(ga.geom), ST_Boundary(gb.geom))",
+		"ST_LineMerge(ST_CollectionExtract(geom, 2)) AS lines",
+		"ST_CollectionExtract(geom, 1) AS points",
+		"ST_Length(lines::geography) AS length_m",
+		"WHERE a.id = $1::uuid AND b.id = $2::uuid",
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("expected %q in query: %s", expected, sql)
+		}
+	}
+	if len(args) != 2 || args[0] != admId || args[1] != neighborId {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestAdmBorderHandlerInvalidIds(t *testing.T) {
+	t.Logf("Test: AdmBorderHandler - rejects invalid and identical ids")
+
+	handler := NewAdmNeighborsHandler(nil)
+	admId := "0f6d4c1e-7c9b-4e0a-9a43-5d1c2b0d8e11"
+	cases := map[string][2]string{
+		"invalid_adm_id": {"not-a-uuid", admId},
+		"same_adm_ids":   {admId, admId},
+	}
+	for expected, ids := range cases {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /adm/{id}/border/{neighbor_id}", handler.AdmBorderHandler)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/adm/"+ids[0]+"/border/"+ids[1], nil))
+		if w.Code != http.StatusBadRequest || strings.TrimSpace(w.Body.String()) != expected {
+			t.Errorf("unexpected response for %v: %d %s", ids, w.Code, w.Body.String())
+		}
+	}
+}
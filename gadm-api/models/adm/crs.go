@@ -0,0 +1,82 @@
+package adm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const CRS84_URI = "http://www.opengis.net/def/crs/OGC/1.3/CRS84"
+const EPSG_URI_PREFIX = "http://www.opengis.net/def/crs/EPSG/0/"
+
+const WEB_MERCATOR_SRID = 3857
+
+var errUnsupportedCrs = errors.New("unsupported_crs")
+
+type crs struct {
+	srid int
+	uri  string
+	// EPSG geographic CRSs are defined with latitude first while PostGIS
+	// always stores longitude first, coordinates have to be swapped.
+	flipAxes bool
+}
+
+// Geometries are stored in EPSG:4326 with longitude first which is
+// exactly what OGC CRS84 describes.
+var defaultCrs = crs{srid: 4326, uri: CRS84_URI}
+
+func (c crs) isDefault() bool {
+	return c.srid == defaultCrs.srid && !c.flipAxes
+}
+
+// Accepts OGC URIs, URNs and EPSG:<code> shorthands. Whether the SRID is
+// known to PostGIS has to be checked separately.
+func parseCrs(value string) (crs, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToUpper(value) {
+	case "", "CRS84", "OGC:CRS84", strings.ToUpper(CRS84_URI), "URN:OGC:DEF:CRS:OGC:1.3:CRS84":
+		return defaultCrs, nil
+	}
+
+	var code string
+	upperValue := strings.ToUpper(value)
+	switch {
+	case strings.HasPrefix(upperValue, "EPSG:"):
+		code = value[len("EPSG:"):]
+	case strings.HasPrefix(upperValue, "URN:OGC:DEF:CRS:EPSG:"):
+		code = value[strings.LastIndex(value, ":")+1:]
+	case strings.HasPrefix(value, EPSG_URI_PREFIX):
+		code = value[len(EPSG_URI_PREFIX):]
+	case strings.HasPrefix(value, "https://www.opengis.net/def/crs/EPSG/0/"):
+		code = value[len("https://www.opengis.net/def/crs/EPSG/0/"):]
+	default:
+		return crs{}, fmt.Errorf("%w: crs=%s", errUnsupportedCrs, value)
+	}
+
+	srid, err := strconv.Atoi(code)
+	if err != nil || srid <= 0 {
+		return crs{}, fmt.Errorf("%w: crs=%s", errUnsupportedCrs, value)
+	}
+
+	return crs{srid: srid, uri: fmt.Sprintf("%s%d", EPSG_URI_PREFIX, srid)}, nil
+}
+
+// SQL expression transforming geometry column into the crs.
+func (c crs) transformSql(geom string) string {
+	if c.isDefault() {
+		return geom
+	}
+	if c.srid == WEB_MERCATOR_SRID {
+		// poles can not be projected to web mercator
+		geom = fmt.Sprintf(
+			"ST_ClipByBox2D(%s, ST_MakeEnvelope(-180, -85.06, 180, 85.06, 4326))",
+			geom,
+		)
+	}
+	transformed := fmt.Sprintf("ST_Transform(%s, %d)", geom, c.srid)
+	if c.flipAxes {
+		return fmt.Sprintf("ST_FlipCoordinates(%s)", transformed)
+	}
+	return transformed
+}
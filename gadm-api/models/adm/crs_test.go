@@ -0,0 +1,66 @@
+package adm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseCrs(t *testing.T) {
+	t.Logf("Test: parseCrs - supported notations")
+
+	testCases := []struct {
+		value string
+		srid  int
+		uri   string
+	}{
+		{value: "", srid: 4326, uri: CRS84_URI},
+		{value: "CRS84", srid: 4326, uri: CRS84_URI},
+		{value: CRS84_URI, srid: 4326, uri: CRS84_URI},
+		{value: "EPSG:3857", srid: 3857, uri: "http://www.opengis.net/def/crs/EPSG/0/3857"},
+		{value: "epsg:2180", srid: 2180, uri: "http://www.opengis.net/def/crs/EPSG/0/2180"},
+		{value: "http://www.opengis.net/def/crs/EPSG/0/2180", srid: 2180, uri: "http://www.opengis.net/def/crs/EPSG/0/2180"},
+		{value: "urn:ogc:def:crs:EPSG::4326", srid: 4326, uri: "http://www.opengis.net/def/crs/EPSG/0/4326"},
+	}
+
+	for _, testCase := range testCases {
+		result, err := parseCrs(testCase.value)
+		if err != nil {
+			t.Errorf("unexpected error for crs '%s': %v", testCase.value, err)
+			continue
+		}
+		if result.srid != testCase.srid || result.uri != testCase.uri {
+			t.Errorf(
+				"unexpected crs for '%s': srid=%d uri=%s, expected srid=%d uri=%s",
+				testCase.value, result.srid, result.uri, testCase.srid, testCase.uri)
+		}
+	}
+}
+
+func TestParseCrsInvalid(t *testing.T) {
+	t.Logf("Test: parseCrs - unsupported notations")
+
+	invalidValues := []string{"EPSG:abc", "EPSG:-1", "ESRI:102100", "http://example.com/crs/4326"}
+
+	for _, value := range invalidValues {
+		_, err := parseCrs(value)
+		if err == nil {
+			t.Errorf("expected error for crs '%s'", value)
+		} else if !errors.Is(err, errUnsupportedCrs) {
+			t.Errorf("unexpected error for crs '%s': %v", value, err)
+		}
+	}
+}
+
+func TestCrsTransformSql(t *testing.T) {
+	t.Logf("Test: crs.transformSql - default crs is not transformed")
+
+	if sql := defaultCrs.transformSql("g.geom"); sql != "g.geom" {
+		t.Errorf("unexpected transform for default crs: %s", sql)
+	}
+
+	epsg4326 := crs{srid: 4326, uri: EPSG_URI_PREFIX + "4326", flipAxes: true}
+	expected := "ST_FlipCoordinates(ST_Transform(g.geom, 4326))"
+	if sql := epsg4326.transformSql("g.geom"); sql != expected {
+		t.Errorf("unexpected transform for EPSG:4326: '%s'. Expected '%s'", sql, expected)
+	}
+}
@@ -0,0 +1,202 @@
+package adm
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// RFC 4180 output, see https://www.rfc-editor.org/rfc/rfc4180
+
+const CSV_CONTENT_TYPE = "text/csv; charset=utf-8; header=present"
+const MAX_CSV_COLUMNS = 100
+
+var utf8Bom = []byte{0xEF, 0xBB, 0xBF}
+
+type csvGeometry string
+
+const (
+	csvGeometryNone     csvGeometry = "none"
+	csvGeometryWkt      csvGeometry = "wkt"
+	csvGeometryWkb      csvGeometry = "wkb"
+	csvGeometryCentroid csvGeometry = "centroid"
+)
+
+func getCsvGeometryFromString(value string) (csvGeometry, error) {
+	switch csvGeometry(value) {
+	case "":
+		return csvGeometryNone, nil
+	case csvGeometryNone, csvGeometryWkt, csvGeometryWkb, csvGeometryCentroid:
+		return csvGeometry(value), nil
+	default:
+		return "", fmt.Errorf("invalid_csv_geometry: %s", value)
+	}
+}
+
+func (geometry csvGeometry) columns() []string {
+	switch geometry {
+	case csvGeometryWkt:
+		return []string{"wkt"}
+	case csvGeometryWkb:
+		return []string{"wkb"}
+	case csvGeometryCentroid:
+		return []string{"centroid_lat", "centroid_lng"}
+	default:
+		return nil
+	}
+}
+
+// Columns taken from adm row, any other column is a metadata key.
+var csvAdmColumns = []string{"id", "lv", "geom_hash", "area_sq_m"}
+
+func getCsvColumnsFromString(value string) ([]string, error) {
+	return getMetadataKeysFromString(value, MAX_CSV_COLUMNS)
+}
+
+// Default columns when none are requested, every metadata key of the
+// exported adms follows the adm columns.
+func getDefaultCsvColumns(metadataKeys []admMetadataKey) []string {
+	columns := []string{"id", "lv", "geom_hash"}
+	for _, key := range metadataKeys {
+		if !isCsvAdmColumn(key.Name) {
+			columns = append(columns, key.Name)
+		}
+	}
+	return columns
+}
+
+// Default columns of a single page, taken from metadata of its own adms so
+// that a page does not scan every adm matching the filter.
+func getDefaultCsvColumnsFromAdms(adms []Adm) ([]string, error) {
+	names := map[string]bool{}
+	for _, adm := range adms {
+		metadata := map[string]json.RawMessage{}
+		if len(adm.Metadata) > 0 {
+			if err := json.Unmarshal(adm.Metadata, &metadata); err != nil {
+				return nil, fmt.Errorf("failed_to_unmarshal_metadata: adm_id=%s: %w", adm.ID, err)
+			}
+		}
+		for name := range metadata {
+			names[name] = true
+		}
+	}
+
+	metadataKeys := make([]admMetadataKey, 0, len(names))
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		metadataKeys = append(metadataKeys, admMetadataKey{Name: name})
+	}
+	return getDefaultCsvColumns(metadataKeys), nil
+}
+
+func isCsvAdmColumn(column string) bool {
+	for _, admColumn := range csvAdmColumns {
+		if column == admColumn {
+			return true
+		}
+	}
+	return false
+}
+
+type admCsvEncoder struct {
+	columns  []string
+	geometry csvGeometry
+	buf      bytes.Buffer
+	writer   *csv.Writer
+}
+
+func newAdmCsvEncoder(columns []string, geometry csvGeometry) *admCsvEncoder {
+	encoder := &admCsvEncoder{columns: columns, geometry: geometry}
+	encoder.writer = csv.NewWriter(&encoder.buf)
+	encoder.writer.UseCRLF = true
+	return encoder
+}
+
+func (encoder *admCsvEncoder) encodeRecord(record []string) ([]byte, error) {
+	encoder.buf.Reset()
+	if err := encoder.writer.Write(record); err != nil {
+		return nil, fmt.Errorf("failed_to_write_csv_record: %w", err)
+	}
+	encoder.writer.Flush()
+	if err := encoder.writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed_to_write_csv_record: %w", err)
+	}
+	return bytes.Clone(encoder.buf.Bytes()), nil
+}
+
+func (encoder *admCsvEncoder) encodeHeader() ([]byte, error) {
+	return encoder.encodeRecord(append(append([]string{}, encoder.columns...), encoder.geometry.columns()...))
+}
+
+func (encoder *admCsvEncoder) encodeAdm(adm Adm) ([]byte, error) {
+	metadata := map[string]json.RawMessage{}
+	if len(adm.Metadata) > 0 {
+		if err := json.Unmarshal(adm.Metadata, &metadata); err != nil {
+			return nil, fmt.Errorf("failed_to_unmarshal_metadata: adm_id=%s: %w", adm.ID, err)
+		}
+	}
+
+	record := make([]string, 0, len(encoder.columns)+2)
+	for _, column := range encoder.columns {
+		switch column {
+		case "id":
+			record = append(record, adm.ID)
+		case "lv":
+			record = append(record, strconv.Itoa(adm.Level))
+		case "geom_hash":
+			record = append(record, adm.GeomHash)
+		case "area_sq_m":
+			record = append(record, formatCsvFloat(adm.AreaSqM))
+		default:
+			value, err := formatCsvJsonValue(metadata[column])
+			if err != nil {
+				return nil, fmt.Errorf("failed_to_format_csv_value: adm_id=%s column=%s: %w", adm.ID, column, err)
+			}
+			record = append(record, value)
+		}
+	}
+
+	switch encoder.geometry {
+	case csvGeometryWkt:
+		record = append(record, formatCsvString(adm.Wkt))
+	case csvGeometryWkb:
+		record = append(record, formatCsvString(adm.Wkb))
+	case csvGeometryCentroid:
+		record = append(record, formatCsvFloat(adm.CentroidLat), formatCsvFloat(adm.CentroidLng))
+	}
+
+	return encoder.encodeRecord(record)
+}
+
+func formatCsvFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func formatCsvString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// Strings are written without JSON quotes, null as empty cell and
+// numbers, booleans, objects and arrays as JSON.
+func formatCsvJsonValue(value json.RawMessage) (string, error) {
+	if len(value) == 0 || string(value) == "null" {
+		return "", nil
+	}
+	if value[0] == '"' {
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+	return string(value), nil
+}
@@ -0,0 +1,73 @@
+package adm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAdmCsvEncoder(t *testing.T) {
+	t.Logf("Test: admCsvEncoder - RFC 4180 quoting and line endings")
+
+	encoder := newAdmCsvEncoder([]string{"id", "lv", "name_1", "fid", "varname_1", "missing"}, csvGeometryCentroid)
+
+	header, err := encoder.encodeHeader()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedHeader := "id,lv,name_1,fid,varname_1,missing,centroid_lat,centroid_lng\r\n"
+	if string(header) != expectedHeader {
+		t.Errorf("unexpected header: %q. Expected %q", header, expectedHeader)
+	}
+
+	lat, lng := 52.1, 21.25
+	row, err := encoder.encodeAdm(Adm{
+		ID:          "a",
+		Level:       1,
+		Metadata:    []byte(`{"name_1": "Łódź, \"city\"", "fid": 12, "varname_1": null}`),
+		CentroidLat: &lat,
+		CentroidLng: &lng,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "a,1,\"Łódź, \"\"city\"\"\",12,,,52.1,21.25\r\n"
+	if string(row) != expectedRow {
+		t.Errorf("unexpected row: %q. Expected %q", row, expectedRow)
+	}
+}
+
+func TestGetCsvColumnsFromString(t *testing.T) {
+	t.Logf("Test: getCsvColumnsFromString - rejects invalid column names")
+
+	columns, err := getCsvColumnsFromString("id, gid_1 ,name_1")
+	if err != nil || len(columns) != 3 || columns[1] != "gid_1" {
+		t.Errorf("unexpected columns: %v, err: %v", columns, err)
+	}
+
+	for _, value := range []string{"id,", "name;drop", "a b"} {
+		if _, err := getCsvColumnsFromString(value); err == nil {
+			t.Errorf("expected error for columns '%s'", value)
+		}
+	}
+}
+
+func TestGetDefaultCsvColumnsFromAdms(t *testing.T) {
+	t.Logf("Test: getDefaultCsvColumnsFromAdms - sorted metadata keys of page adms")
+
+	columns, err := getDefaultCsvColumnsFromAdms([]Adm{
+		{ID: "a", Metadata: []byte(`{"name_1":"Pomorskie","gid_1":"POL.11_1"}`)},
+		{ID: "b", Metadata: []byte(`{"name_1":"Kujawsko-Pomorskie","varname_1":null,"lv":1}`)},
+		{ID: "c"},
+	})
+	if err != nil {
+		t.Fatalf("failed to get default columns: %v", err)
+	}
+	expected := []string{"id", "lv", "geom_hash", "gid_1", "name_1", "varname_1"}
+	if !slices.Equal(columns, expected) {
+		t.Errorf("unexpected columns: %v. Expected %v", columns, expected)
+	}
+
+	if _, err := getDefaultCsvColumnsFromAdms([]Adm{{ID: "a", Metadata: []byte(`[`)}}); err == nil {
+		t.Errorf("expected error for invalid metadata")
+	}
+}
@@ -0,0 +1,281 @@
+package adm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type admSortKey string
+
+const (
+	admSortId   admSortKey = "id"
+	admSortName admSortKey = "name"
+	admSortGid  admSortKey = "gid"
+	admSortArea admSortKey = "area_sq_m"
+)
+
+// Sort expressions only read gadm.adm and gadm.adm_geometries, so pages stay
+// stable while derived tables like adm_tree and adm_neighbors are rebuilt.
+var admSortSqlExprs = map[admSortKey]string{
+	admSortId:   "adm.id",
+	admSortName: "COALESCE(adm.metadata ->> ('name_' || adm.lv::text), adm.metadata ->> 'country', '')",
+	admSortGid:  "COALESCE(adm.metadata ->> ('gid_' || adm.lv::text), '')",
+	admSortArea: "g.area_sq_m",
+}
+
+// Zero value sorts by id ascending. Ties are broken by id, so that every
+// adm has a unique position that a cursor can point to.
+type admSort struct {
+	key  admSortKey
+	desc bool
+}
+
+// Parses `name` or `-name` for descending order.
+func getAdmSortFromString(value string) (admSort, error) {
+	if value == "" {
+		return admSort{}, nil
+	}
+	sort := admSort{key: admSortKey(strings.TrimPrefix(value, "-")), desc: strings.HasPrefix(value, "-")}
+	if _, ok := admSortSqlExprs[sort.key]; !ok {
+		return admSort{}, fmt.Errorf("invalid_sort: %s", value)
+	}
+	return sort, nil
+}
+
+func (sort admSort) isDefault() bool {
+	return (sort.key == "" || sort.key == admSortId) && !sort.desc
+}
+
+func (sort admSort) String() string {
+	key := sort.key
+	if key == "" {
+		key = admSortId
+	}
+	if sort.desc {
+		return "-" + string(key)
+	}
+	return string(key)
+}
+
+func (sort admSort) sqlExpr() string {
+	if sort.key == "" {
+		return admSortSqlExprs[admSortId]
+	}
+	return admSortSqlExprs[sort.key]
+}
+
+// Sort value is selected for keys other than id, cursors are built from it.
+func (sort admSort) needsSortValue() bool {
+	return sort.key != "" && sort.key != admSortId
+}
+
+// Sort values decoded from cursor JSON, strings for text keys and numbers
+// for area. NULL boundary would select no adms.
+func (sort admSort) isValidSortValue(value any) bool {
+	switch sort.key {
+	case admSortName, admSortGid:
+		_, ok := value.(string)
+		return ok
+	case admSortArea:
+		_, ok := value.(float64)
+		return ok
+	default:
+		return true
+	}
+}
+
+// Page boundary, adms after it in sort order are selected, or before it
+// when going backward.
+type admKeyset struct {
+	sortValue any
+	id        string
+	backward  bool
+}
+
+func newAdmKeyset(adm Adm, backward bool) *admKeyset {
+	return &admKeyset{sortValue: adm.SortValue, id: adm.ID, backward: backward}
+}
+
+var errInvalidCursor = errors.New("invalid_cursor")
+
+type admCursor struct {
+	// kept when zero, '' or 0 are valid boundaries
+	SortValue any    `json:"k"`
+	Id        string `json:"i"`
+	Backward  bool   `json:"b,omitempty"`
+	// hash of filters and sort, cursor is rejected with different ones
+	Filter string `json:"f"`
+}
+
+// Encodes keysets into opaque tokens signed with HMAC-SHA256, so that
+// clients can't craft cursors or reuse them with different filters.
+type cursorCodec struct {
+	secret []byte
+}
+
+func NewCursorCodec(secret []byte) *cursorCodec {
+	return &cursorCodec{secret: secret}
+}
+
+func getAdmCursorFilter(opts admQueryOpts) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sort=%s", opts.sort.String())
+	if opts.lv != nil {
+		fmt.Fprintf(&b, "&lv=%d", *opts.lv)
+	}
+	if opts.country != nil {
+		fmt.Fprintf(&b, "&country=%s", *opts.country)
+	}
+	if opts.bbox != nil {
+		fmt.Fprintf(&b, "&bbox=%s", opts.bbox.String())
+	}
+	if opts.parentId != nil {
+		fmt.Fprintf(&b, "&parent-id=%s", *opts.parentId)
+	}
+	hash := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(hash[:8])
+}
+
+func (codec *cursorCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, codec.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (codec *cursorCodec) encode(keyset admKeyset, opts admQueryOpts) (string, error) {
+	data, err := json.Marshal(admCursor{
+		SortValue: keyset.sortValue,
+		Id:        keyset.id,
+		Backward:  keyset.backward,
+		Filter:    getAdmCursorFilter(opts),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed_to_marshal_cursor: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + codec.sign(payload), nil
+}
+
+func (codec *cursorCodec) decode(token string, opts admQueryOpts) (admKeyset, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(codec.sign(payload))) {
+		return admKeyset{}, fmt.Errorf("%w: bad_signature", errInvalidCursor)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return admKeyset{}, fmt.Errorf("%w: %v", errInvalidCursor, err)
+	}
+	var cursor admCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return admKeyset{}, fmt.Errorf("%w: %v", errInvalidCursor, err)
+	}
+	if cursor.Filter != getAdmCursorFilter(opts) {
+		return admKeyset{}, fmt.Errorf("%w: filter_mismatch", errInvalidCursor)
+	}
+	if _, err := uuid.Parse(cursor.Id); err != nil {
+		return admKeyset{}, fmt.Errorf("%w: %v", errInvalidCursor, err)
+	}
+	if !opts.sort.isValidSortValue(cursor.SortValue) {
+		return admKeyset{}, fmt.Errorf("%w: invalid_sort_value", errInvalidCursor)
+	}
+	return admKeyset{sortValue: cursor.SortValue, id: cursor.Id, backward: cursor.Backward}, nil
+}
+
+// Applies sort and cursor query params of paginated endpoints. Cursor only
+// works with filters and sort of the request it was issued for.
+func getAdmPageOptsFromRequest(r *http.Request, opts admQueryOpts, codec *cursorCodec) (admQueryOpts, error) {
+	sort, err := getAdmSortFromString(r.URL.Query().Get("sort"))
+	if err != nil {
+		return opts, fmt.Errorf("failed_parsing_query_param_sort: %w", err)
+	}
+	opts.sort = sort
+
+	if opts.startAfterId != nil && opts.startAfterFid != nil {
+		return opts, errors.New("start_after_id_and_start_after_fid_are_exclusive")
+	}
+	hasStartAfter := opts.startAfterId != nil || opts.startAfterFid != nil
+	if hasStartAfter && !sort.isDefault() {
+		return opts, errors.New("start_after_requires_default_sort")
+	}
+
+	token := r.URL.Query().Get("cursor")
+	if token == "" {
+		return opts, nil
+	}
+	if hasStartAfter {
+		return opts, errors.New("cursor_and_start_after_are_exclusive")
+	}
+	keyset, err := codec.decode(token, opts)
+	if err != nil {
+		return opts, err
+	}
+	opts.keyset = &keyset
+	return opts, nil
+}
+
+func getAdmsPageUrl(baseUrl url.URL, opts admQueryOpts, cursor string) string {
+	query := baseUrl.Query()
+	query.Set("cursor", cursor)
+	if !opts.sort.isDefault() {
+		query.Set("sort", opts.sort.String())
+	}
+	setAdmsQueryParams(query, opts)
+	baseUrl.RawQuery = query.Encode()
+	return baseUrl.String()
+}
+
+// Links to neighboring pages, legacy fid pagination only links next page.
+func getAdmsPageLinks(
+	baseUrl url.URL,
+	opts admQueryOpts,
+	page admPage,
+	codec *cursorCodec,
+	mediaType string,
+) ([]link, error) {
+	links := []link{}
+	if opts.startAfterFid != nil {
+		if page.hasNext {
+			lastAdm, err := convertAdmsToGeojson(page.adms[len(page.adms)-1])
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, link{Href: getAdmsNextUrl(baseUrl, lastAdm, opts), Rel: "next", Type: mediaType})
+		}
+		return links, nil
+	}
+
+	for _, boundary := range []struct {
+		keyset *admKeyset
+		rel    string
+	}{{page.next, "next"}, {page.prev, "prev"}} {
+		if boundary.keyset == nil {
+			continue
+		}
+		cursor, err := codec.encode(*boundary.keyset, opts)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link{Href: getAdmsPageUrl(baseUrl, opts, cursor), Rel: boundary.rel, Type: mediaType})
+	}
+	return links, nil
+}
+
+func setLinkHeader(w http.ResponseWriter, links []link) {
+	values := []string{}
+	for _, l := range links {
+		values = append(values, fmt.Sprintf("<%s>; rel=\"%s\"", l.Href, l.Rel))
+	}
+	if len(values) > 0 {
+		w.Header().Set("Link", strings.Join(values, ", "))
+	}
+}
@@ -0,0 +1,127 @@
+package adm
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetAdmSortFromString(t *testing.T) {
+	t.Logf("Test: getAdmSortFromString - sort keys and direction")
+
+	cases := map[string]admSort{
+		"":           {},
+		"id":         {key: admSortId},
+		"-name":      {key: admSortName, desc: true},
+		"area_sq_m":  {key: admSortArea},
+		"-gid":       {key: admSortGid, desc: true},
+		"-area_sq_m": {key: admSortArea, desc: true},
+	}
+	for value, expected := range cases {
+		sort, err := getAdmSortFromString(value)
+		if err != nil || sort != expected {
+			t.Errorf("unexpected sort for %q: %+v %v. Expected %+v", value, sort, err, expected)
+		}
+	}
+
+	for _, value := range []string{"fid", "--name", "name,id"} {
+		if _, err := getAdmSortFromString(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestCursorCodec(t *testing.T) {
+	t.Logf("Test: cursorCodec - round trip, tampering and filter mismatch")
+
+	codec := NewCursorCodec([]byte("secret"))
+	lv := 2
+	country := "POL"
+	opts := admQueryOpts{lv: &lv, country: &country, sort: admSort{key: admSortArea, desc: true}}
+	keyset := admKeyset{sortValue: 1234.5, id: "0f6d4c1e-7c9b-4e0a-9a43-5d1c2b0d8e11", backward: true}
+
+	token, err := codec.encode(keyset, opts)
+	if err != nil {
+		t.Fatalf("failed to encode cursor: %v", err)
+	}
+	decoded, err := codec.decode(token, opts)
+	if err != nil {
+		t.Fatalf("failed to decode cursor: %v", err)
+	}
+	if decoded != keyset {
+		t.Errorf("unexpected keyset: %+v. Expected %+v", decoded, keyset)
+	}
+
+	for _, c := range []struct {
+		sortValue any
+		sort      admSort
+	}{{"", admSort{key: admSortName}}, {0.0, admSort{key: admSortArea}}} {
+		zeroOpts := admQueryOpts{sort: c.sort}
+		zeroToken, _ := codec.encode(admKeyset{sortValue: c.sortValue, id: keyset.id}, zeroOpts)
+		zeroKeyset, err := codec.decode(zeroToken, zeroOpts)
+		if err != nil || zeroKeyset.sortValue != c.sortValue {
+			t.Errorf("expected zero sort value %#v to be kept, got %#v %v", c.sortValue, zeroKeyset.sortValue, err)
+		}
+	}
+
+	payload, _, _ := strings.Cut(token, ".")
+	otherToken, _ := NewCursorCodec([]byte("other")).encode(keyset, opts)
+	_, otherSignature, _ := strings.Cut(otherToken, ".")
+	otherCountry := "DEU"
+	otherOpts := opts
+	otherOpts.country = &otherCountry
+	sortedOpts := opts
+	sortedOpts.sort = admSort{key: admSortName}
+	nameOpts := admQueryOpts{sort: admSort{key: admSortName}}
+	nullToken, _ := codec.encode(admKeyset{id: keyset.id}, nameOpts)
+
+	invalid := []struct {
+		token string
+		opts  admQueryOpts
+	}{
+		{payload + "." + otherSignature, opts},
+		{payload, opts},
+		{"", opts},
+		{token, otherOpts},
+		{token, sortedOpts},
+		{nullToken, nameOpts},
+	}
+	for _, c := range invalid {
+		if _, err := codec.decode(c.token, c.opts); !errors.Is(err, errInvalidCursor) {
+			t.Errorf("expected invalid cursor for %q, got %v", c.token, err)
+		}
+	}
+}
+
+func TestGetAdmPageOptsFromRequest(t *testing.T) {
+	t.Logf("Test: getAdmPageOptsFromRequest - cursor and start-after conflicts")
+
+	codec := NewCursorCodec([]byte("secret"))
+	startAfterId := "0f6d4c1e-7c9b-4e0a-9a43-5d1c2b0d8e11"
+	startAfterFid := "100"
+	token, _ := codec.encode(admKeyset{id: startAfterId}, admQueryOpts{})
+
+	cases := []struct {
+		url   string
+		opts  admQueryOpts
+		valid bool
+	}{
+		{"/fc?sort=-name", admQueryOpts{}, true},
+		{"/fc?cursor=" + token, admQueryOpts{}, true},
+		{"/fc?sort=name", admQueryOpts{startAfterId: &startAfterId}, false},
+		{"/fc", admQueryOpts{startAfterId: &startAfterId, startAfterFid: &startAfterFid}, false},
+		{"/fc?cursor=" + token, admQueryOpts{startAfterId: &startAfterId}, false},
+		{"/fc?sort=name&cursor=" + token, admQueryOpts{}, false},
+		{"/fc?sort=size", admQueryOpts{}, false},
+	}
+	for _, c := range cases {
+		opts, err := getAdmPageOptsFromRequest(httptest.NewRequest("GET", c.url, nil), c.opts, codec)
+		if (err == nil) != c.valid {
+			t.Errorf("unexpected result for %s: %v", c.url, err)
+		}
+		if err == nil && strings.Contains(c.url, "cursor=") && (opts.keyset == nil || opts.keyset.id != startAfterId) {
+			t.Errorf("expected keyset from cursor for %s, got %+v", c.url, opts.keyset)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package adm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Formats of asynchronous exports, see export_job package.
+const (
+	ExportFormatGeojsonl   = "geojsonl"
+	ExportFormatCsv        = "csv"
+	ExportFormatFlatgeobuf = "fgb"
+	ExportFormatGeoparquet = "parquet"
+)
+
+var ExportFormats = []string{ExportFormatGeojsonl, ExportFormatCsv, ExportFormatFlatgeobuf, ExportFormatGeoparquet}
+
+var ErrInvalidExportFilter = errors.New("invalid_export_filter")
+
+// Progress is reported every EXPORT_PROGRESS_INTERVAL adms and once done.
+const EXPORT_PROGRESS_INTERVAL = 100
+
+type ExportFilter struct {
+	Lv      *int        `json:"lv,omitempty"`
+	Country string      `json:"country,omitempty"`
+	Bbox    *[4]float64 `json:"bbox,omitempty"`
+}
+
+func (filter ExportFilter) getAdmQueryOpts() (admQueryOpts, error) {
+	if filter.Lv != nil && (*filter.Lv < 0 || *filter.Lv > 5) {
+		return admQueryOpts{}, fmt.Errorf("%w: lv=%d", ErrInvalidExportFilter, *filter.Lv)
+	}
+
+	var _bbox *bbox
+	if filter.Bbox != nil {
+		b := bbox(*filter.Bbox)
+		minx, miny, maxx, maxy := b[0], b[1], b[2], b[3]
+		if minx < -180 || maxx > 180 || miny < -90 || maxy > 90 || miny > maxy {
+			return admQueryOpts{}, fmt.Errorf("%w: bbox=%s", ErrInvalidExportFilter, b.String())
+		}
+		_bbox = &b
+	}
+
+	return NewAdmQueryOptsBuilder().
+		SetLvAndBatchSize(filter.Lv, nil).
+		SetCountry(filter.Country).
+		SetBbox(_bbox).
+		Build()
+}
+
+func (filter ExportFilter) Validate() error {
+	_, err := filter.getAdmQueryOpts()
+	return err
+}
+
+func (service *Service) CountAdmsForExport(ctx context.Context, filter ExportFilter) (int64, error) {
+	opts, err := filter.getAdmQueryOpts()
+	if err != nil {
+		return 0, err
+	}
+	return service.repo.CountAdms(ctx, opts)
+}
+
+// Writes adms matching filter in given format, onProgress is called with
+// the number of adms written so far.
+func (service *Service) WriteAdmsExport(
+	ctx context.Context,
+	w io.Writer,
+	format string,
+	filter ExportFilter,
+	onProgress func(count int64),
+) error {
+	opts, err := filter.getAdmQueryOpts()
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ExportFormatGeojsonl:
+		opts.projection = admProjection{geometry: admGeometryFull}
+		return service.writeAdms(ctx, opts, onProgress, func(ctx context.Context, admCh <-chan Adm) error {
+			return writeGeojsonl(ctx, w, admCh)
+		})
+	case ExportFormatCsv:
+		opts.projection = admProjection{geometry: admGeometryNone}
+		opts.csvGeometry = csvGeometryWkt
+		columns, err := service.GetDefaultCsvColumns(ctx, opts)
+		if err != nil {
+			return err
+		}
+		opts.projection.fields = []string{}
+		for _, column := range columns {
+			if !isCsvAdmColumn(column) {
+				opts.projection.fields = append(opts.projection.fields, column)
+			}
+		}
+		encoder := newAdmCsvEncoder(columns, opts.csvGeometry)
+		return service.writeAdms(ctx, opts, onProgress, func(ctx context.Context, admCh <-chan Adm) error {
+			return writeCsv(ctx, w, encoder, admCh)
+		})
+	case ExportFormatFlatgeobuf:
+		opts.projection = admProjection{geometry: admGeometryFull}
+		columns, err := service.GetFlatgeobufColumns(ctx, opts)
+		if err != nil {
+			return err
+		}
+		header := fgbHeader{name: "adm", columns: columns, srid: opts.crs.srid}
+		return service.writeAdms(ctx, opts, onProgress, func(ctx context.Context, admCh <-chan Adm) error {
+			return writeFgbStream(ctx, w, header, admCh)
+		})
+	case ExportFormatGeoparquet:
+		return service.writeAdmsGeoparquet(ctx, w, opts, onProgress)
+	default:
+		return fmt.Errorf("unsupported_export_format: %s", format)
+	}
+}
+
+// Streams adms queried with opts into write, counting adms that pass.
+func (service *Service) writeAdms(
+	ctx context.Context,
+	opts admQueryOpts,
+	onProgress func(count int64),
+	write func(ctx context.Context, admCh <-chan Adm) error,
+) error {
+	g, gctx := errgroup.WithContext(ctx)
+	admCh := make(chan Adm, 10)
+	g.Go(func() error {
+		return service.repo.GetGeojsonl(gctx, opts, admCh)
+	})
+
+	countedCh := admCh
+	if onProgress != nil {
+		countedCh = make(chan Adm, 10)
+		g.Go(func() error {
+			return countAdms(gctx, admCh, countedCh, onProgress)
+		})
+	}
+
+	g.Go(func() error {
+		// producer must not be left blocked when writing fails
+		defer func() {
+			for range countedCh {
+			}
+		}()
+		return write(gctx, countedCh)
+	})
+	return g.Wait()
+}
+
+func countAdms(ctx context.Context, in <-chan Adm, out chan<- Adm, onProgress func(count int64)) error {
+	defer close(out)
+	defer func() {
+		for range in {
+		}
+	}()
+
+	count := int64(0)
+	for adm := range in {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- adm:
+		}
+		count++
+		if count%EXPORT_PROGRESS_INTERVAL == 0 {
+			onProgress(count)
+		}
+	}
+	onProgress(count)
+	return nil
+}
+
+func writeGeojsonl(ctx context.Context, w io.Writer, admCh <-chan Adm) error {
+	for adm := range admCh {
+		feature, err := convertAdmsToGeojson(adm)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(feature)
+		if err != nil {
+			return fmt.Errorf("failed_to_marshal_feature: adm_id=%s: %w", adm.ID, err)
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed_to_write_data: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCsv(ctx context.Context, w io.Writer, encoder *admCsvEncoder, admCh <-chan Adm) error {
+	header, err := encoder.encodeHeader()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("failed_to_write_data: %w", err)
+	}
+	for adm := range admCh {
+		data, err := encoder.encodeAdm(adm)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed_to_write_data: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,567 @@
+package adm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// FlatGeobuf encoding, see https://flatgeobuf.org and the flatbuffers
+// schemas https://github.com/flatgeobuf/flatgeobuf/tree/master/src/fbs
+
+var fgbMagicBytes = []byte{0x66, 0x67, 0x62, 0x03, 0x66, 0x67, 0x62, 0x01}
+
+const FGB_INDEX_NODE_SIZE = 16
+
+// Indexed exports are buffered in memory to be sorted before writing.
+const MAX_FGB_INDEXED_FEATURES = 50000
+
+var errFgbIndexFeatureLimit = errors.New("fgb_index_feature_limit_exceeded")
+
+const (
+	fgbGeometryTypePolygon      byte = 3
+	fgbGeometryTypeMultiPolygon byte = 6
+)
+
+type fgbColumnType byte
+
+const (
+	fgbColumnTypeBool   fgbColumnType = 2
+	fgbColumnTypeInt    fgbColumnType = 5
+	fgbColumnTypeLong   fgbColumnType = 7
+	fgbColumnTypeDouble fgbColumnType = 10
+	fgbColumnTypeString fgbColumnType = 11
+	fgbColumnTypeJson   fgbColumnType = 12
+)
+
+type fgbColumn struct {
+	name       string
+	columnType fgbColumnType
+}
+
+// Columns always present in features, the rest is built from metadata keys.
+var fgbAdmColumns = []fgbColumn{
+	{name: "id", columnType: fgbColumnTypeString},
+	{name: "lv", columnType: fgbColumnTypeInt},
+	{name: "geom_hash", columnType: fgbColumnTypeString},
+}
+
+// Maps jsonb types found for metadata key to column type.
+func getFgbColumns(metadataKeys []admMetadataKey) []fgbColumn {
+	columns := append([]fgbColumn{}, fgbAdmColumns...)
+	reserved := map[string]bool{}
+	for _, column := range fgbAdmColumns {
+		reserved[column.name] = true
+	}
+
+	for _, key := range metadataKeys {
+		if reserved[key.Name] {
+			continue
+		}
+		column := fgbColumn{name: key.Name, columnType: fgbColumnTypeJson}
+		switch key.valueType() {
+		case metadataValueTypeString:
+			column.columnType = fgbColumnTypeString
+		case metadataValueTypeBoolean:
+			column.columnType = fgbColumnTypeBool
+		case metadataValueTypeInteger:
+			column.columnType = fgbColumnTypeLong
+		case metadataValueTypeNumber:
+			column.columnType = fgbColumnTypeDouble
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// minx, miny, maxx, maxy
+type fgbEnvelope [4]float64
+
+func newFgbEnvelope() fgbEnvelope {
+	return fgbEnvelope{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+func (e fgbEnvelope) isEmpty() bool {
+	return e[0] > e[2]
+}
+
+func (e *fgbEnvelope) expand(x float64, y float64) {
+	e[0] = math.Min(e[0], x)
+	e[1] = math.Min(e[1], y)
+	e[2] = math.Max(e[2], x)
+	e[3] = math.Max(e[3], y)
+}
+
+func (e *fgbEnvelope) expandEnvelope(other fgbEnvelope) {
+	if other.isEmpty() {
+		return
+	}
+	e.expand(other[0], other[1])
+	e.expand(other[2], other[3])
+}
+
+type fgbHeader struct {
+	name          string
+	columns       []fgbColumn
+	srid          int
+	featuresCount uint64
+	indexNodeSize uint16
+	envelope      *fgbEnvelope
+}
+
+func encodeFgbHeader(builder *flatbuffers.Builder, header fgbHeader) []byte {
+	builder.Reset()
+
+	nameOffset := builder.CreateString(header.name)
+
+	columnOffsets := make([]flatbuffers.UOffsetT, len(header.columns))
+	for i, column := range header.columns {
+		columnNameOffset := builder.CreateString(column.name)
+		builder.StartObject(11)
+		builder.PrependUOffsetTSlot(0, columnNameOffset, 0)
+		builder.PrependByteSlot(1, byte(column.columnType), 0)
+		columnOffsets[i] = builder.EndObject()
+	}
+	builder.StartVector(4, len(columnOffsets), 4)
+	for i := len(columnOffsets) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(columnOffsets[i])
+	}
+	columnsOffset := builder.EndVector(len(columnOffsets))
+
+	orgOffset := builder.CreateString("EPSG")
+	builder.StartObject(6)
+	builder.PrependUOffsetTSlot(0, orgOffset, 0)
+	builder.PrependInt32Slot(1, int32(header.srid), 0)
+	crsOffset := builder.EndObject()
+
+	var envelopeOffset flatbuffers.UOffsetT
+	if header.envelope != nil && !header.envelope.isEmpty() {
+		builder.StartVector(8, 4, 8)
+		for i := 3; i >= 0; i-- {
+			builder.PrependFloat64(header.envelope[i])
+		}
+		envelopeOffset = builder.EndVector(4)
+	}
+
+	builder.StartObject(14)
+	builder.PrependUOffsetTSlot(0, nameOffset, 0)
+	if envelopeOffset != 0 {
+		builder.PrependUOffsetTSlot(1, envelopeOffset, 0)
+	}
+	builder.PrependByteSlot(2, fgbGeometryTypeMultiPolygon, 0)
+	builder.PrependUOffsetTSlot(7, columnsOffset, 0)
+	builder.PrependUint64Slot(8, header.featuresCount, 0)
+	// schema default is 16, 0 marks file without index
+	builder.PrependUint16Slot(9, header.indexNodeSize, 16)
+	builder.PrependUOffsetTSlot(10, crsOffset, 0)
+	builder.FinishSizePrefixed(builder.EndObject())
+
+	return builder.FinishedBytes()
+}
+
+// GADM geometries are multipolygons, polygons are promoted so that the
+// header can declare a single geometry type.
+func buildFgbGeometry(
+	builder *flatbuffers.Builder,
+	geometry *geojson.Geometry,
+	envelope *fgbEnvelope,
+) (flatbuffers.UOffsetT, error) {
+	var polygons [][][][]float64
+	switch geometry.Type {
+	case geojson.GeometryPolygon:
+		polygons = [][][][]float64{geometry.Polygon}
+	case geojson.GeometryMultiPolygon:
+		polygons = geometry.MultiPolygon
+	default:
+		return 0, fmt.Errorf("unsupported_fgb_geometry_type: %s", geometry.Type)
+	}
+
+	partOffsets := make([]flatbuffers.UOffsetT, len(polygons))
+	for i, polygon := range polygons {
+		partOffsets[i] = buildFgbPolygon(builder, polygon, envelope)
+	}
+	builder.StartVector(4, len(partOffsets), 4)
+	for i := len(partOffsets) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(partOffsets[i])
+	}
+	partsOffset := builder.EndVector(len(partOffsets))
+
+	builder.StartObject(8)
+	builder.PrependUOffsetTSlot(7, partsOffset, 0)
+	builder.PrependByteSlot(6, fgbGeometryTypeMultiPolygon, 0)
+	return builder.EndObject(), nil
+}
+
+func buildFgbPolygon(builder *flatbuffers.Builder, polygon [][][]float64, envelope *fgbEnvelope) flatbuffers.UOffsetT {
+	xy := []float64{}
+	ends := []uint32{}
+	for _, ring := range polygon {
+		for _, coords := range ring {
+			xy = append(xy, coords[0], coords[1])
+			envelope.expand(coords[0], coords[1])
+		}
+		ends = append(ends, uint32(len(xy)/2))
+	}
+
+	// ends are only required to split multiple rings
+	var endsOffset flatbuffers.UOffsetT
+	if len(ends) > 1 {
+		builder.StartVector(4, len(ends), 4)
+		for i := len(ends) - 1; i >= 0; i-- {
+			builder.PrependUint32(ends[i])
+		}
+		endsOffset = builder.EndVector(len(ends))
+	}
+
+	builder.StartVector(8, len(xy), 8)
+	for i := len(xy) - 1; i >= 0; i-- {
+		builder.PrependFloat64(xy[i])
+	}
+	xyOffset := builder.EndVector(len(xy))
+
+	builder.StartObject(8)
+	if endsOffset != 0 {
+		builder.PrependUOffsetTSlot(0, endsOffset, 0)
+	}
+	builder.PrependUOffsetTSlot(1, xyOffset, 0)
+	builder.PrependByteSlot(6, fgbGeometryTypePolygon, 0)
+	return builder.EndObject()
+}
+
+// Properties are encoded as sequence of column index followed by value,
+// null values are omitted.
+func encodeFgbProperties(adm Adm, columns []fgbColumn) ([]byte, error) {
+	values := map[string]json.RawMessage{}
+	if len(adm.Metadata) > 0 {
+		if err := json.Unmarshal(adm.Metadata, &values); err != nil {
+			return nil, fmt.Errorf("failed_to_unmarshal_metadata: adm_id=%s: %w", adm.ID, err)
+		}
+	}
+	values["id"], _ = json.Marshal(adm.ID)
+	values["lv"], _ = json.Marshal(adm.Level)
+	values["geom_hash"], _ = json.Marshal(adm.GeomHash)
+
+	var buf bytes.Buffer
+	for i, column := range columns {
+		value, ok := values[column.name]
+		if !ok || len(value) == 0 || string(value) == "null" {
+			continue
+		}
+
+		binary.Write(&buf, binary.LittleEndian, uint16(i))
+		if err := encodeFgbPropertyValue(&buf, column.columnType, value); err != nil {
+			return nil, fmt.Errorf("failed_to_encode_property: adm_id=%s column=%s: %w", adm.ID, column.name, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeFgbPropertyValue(buf *bytes.Buffer, columnType fgbColumnType, value json.RawMessage) error {
+	switch columnType {
+	case fgbColumnTypeBool:
+		var v bool
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case fgbColumnTypeInt, fgbColumnTypeLong, fgbColumnTypeDouble:
+		var v json.Number
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		switch columnType {
+		case fgbColumnTypeInt:
+			binary.Write(buf, binary.LittleEndian, int32(f))
+		case fgbColumnTypeLong:
+			i, err := v.Int64()
+			if err != nil {
+				i = int64(f)
+			}
+			binary.Write(buf, binary.LittleEndian, i)
+		default:
+			binary.Write(buf, binary.LittleEndian, f)
+		}
+	case fgbColumnTypeString:
+		var v string
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+		binary.Write(buf, binary.LittleEndian, uint32(len(v)))
+		buf.WriteString(v)
+	default:
+		binary.Write(buf, binary.LittleEndian, uint32(len(value)))
+		buf.Write(value)
+	}
+	return nil
+}
+
+// Returns size prefixed feature and envelope of its geometry.
+func encodeFgbFeature(builder *flatbuffers.Builder, adm Adm, columns []fgbColumn) ([]byte, fgbEnvelope, error) {
+	builder.Reset()
+	envelope := newFgbEnvelope()
+
+	var geometryOffset flatbuffers.UOffsetT
+	if len(adm.Geom) > 0 {
+		geometry, err := geojson.UnmarshalGeometry(adm.Geom)
+		if err != nil {
+			return nil, envelope, fmt.Errorf("failed_to_unmarshal_geometry: adm_id=%s: %w", adm.ID, err)
+		}
+		geometryOffset, err = buildFgbGeometry(builder, geometry, &envelope)
+		if err != nil {
+			return nil, envelope, fmt.Errorf("failed_to_build_geometry: adm_id=%s: %w", adm.ID, err)
+		}
+	}
+
+	properties, err := encodeFgbProperties(adm, columns)
+	if err != nil {
+		return nil, envelope, err
+	}
+	propertiesOffset := builder.CreateByteVector(properties)
+
+	builder.StartObject(3)
+	if geometryOffset != 0 {
+		builder.PrependUOffsetTSlot(0, geometryOffset, 0)
+	}
+	builder.PrependUOffsetTSlot(1, propertiesOffset, 0)
+	builder.FinishSizePrefixed(builder.EndObject())
+
+	return bytes.Clone(builder.FinishedBytes()), envelope, nil
+}
+
+// Features are written as they arrive, the header declares neither
+// features count nor index.
+func writeFgbStream(ctx context.Context, w io.Writer, header fgbHeader, admCh <-chan Adm) error {
+	builder := flatbuffers.NewBuilder(1024)
+	header.indexNodeSize = 0
+	if _, err := w.Write(fgbMagicBytes); err != nil {
+		return fmt.Errorf("failed_to_write_fgb_magic_bytes: %w", err)
+	}
+	if _, err := w.Write(encodeFgbHeader(builder, header)); err != nil {
+		return fmt.Errorf("failed_to_write_fgb_header: %w", err)
+	}
+
+	for adm := range admCh {
+		feature, _, err := encodeFgbFeature(builder, adm, header.columns)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(feature); err != nil {
+			return fmt.Errorf("failed_to_write_fgb_feature: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+type fgbNodeItem struct {
+	envelope fgbEnvelope
+	offset   uint64
+}
+
+type fgbIndexedFeature struct {
+	data    []byte
+	item    fgbNodeItem
+	hilbert uint32
+}
+
+// Features are buffered and ordered along hilbert curve before the packed
+// R-tree and the features are written in the same order.
+func writeFgbWithIndex(ctx context.Context, w io.Writer, header fgbHeader, admCh <-chan Adm) error {
+	builder := flatbuffers.NewBuilder(1024)
+	features := []fgbIndexedFeature{}
+	extent := newFgbEnvelope()
+	for adm := range admCh {
+		if len(features) >= MAX_FGB_INDEXED_FEATURES {
+			return errFgbIndexFeatureLimit
+		}
+		data, envelope, err := encodeFgbFeature(builder, adm, header.columns)
+		if err != nil {
+			return err
+		}
+		extent.expandEnvelope(envelope)
+		features = append(features, fgbIndexedFeature{data: data, item: fgbNodeItem{envelope: envelope}})
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for i := range features {
+		features[i].hilbert = getFgbHilbertValue(features[i].item.envelope, extent)
+	}
+	sort.SliceStable(features, func(i, j int) bool {
+		return features[i].hilbert < features[j].hilbert
+	})
+
+	leaves := make([]fgbNodeItem, len(features))
+	var offset uint64
+	for i := range features {
+		features[i].item.offset = offset
+		leaves[i] = features[i].item
+		offset += uint64(len(features[i].data))
+	}
+
+	header.featuresCount = uint64(len(features))
+	header.indexNodeSize = FGB_INDEX_NODE_SIZE
+	header.envelope = &extent
+	if len(features) == 0 {
+		header.indexNodeSize = 0
+		header.envelope = nil
+	}
+
+	if _, err := w.Write(fgbMagicBytes); err != nil {
+		return fmt.Errorf("failed_to_write_fgb_magic_bytes: %w", err)
+	}
+	if _, err := w.Write(encodeFgbHeader(builder, header)); err != nil {
+		return fmt.Errorf("failed_to_write_fgb_header: %w", err)
+	}
+	if len(features) > 0 {
+		if _, err := w.Write(encodeFgbPackedRTree(leaves, FGB_INDEX_NODE_SIZE)); err != nil {
+			return fmt.Errorf("failed_to_write_fgb_index: %w", err)
+		}
+	}
+	for _, feature := range features {
+		if _, err := w.Write(feature.data); err != nil {
+			return fmt.Errorf("failed_to_write_fgb_feature: %w", err)
+		}
+	}
+	return nil
+}
+
+// Index ranges of nodes on each tree level, starting with leaves which are
+// stored at the end. Root is the first node.
+func getFgbRTreeLevelBounds(numItems int, nodeSize int) [][2]int {
+	n := numItems
+	numNodes := n
+	levelNumNodes := []int{n}
+	for {
+		n = (n + nodeSize - 1) / nodeSize
+		numNodes += n
+		levelNumNodes = append(levelNumNodes, n)
+		if n == 1 {
+			break
+		}
+	}
+
+	levelBounds := make([][2]int, len(levelNumNodes))
+	n = numNodes
+	for i, size := range levelNumNodes {
+		levelBounds[i] = [2]int{n - size, n}
+		n -= size
+	}
+	return levelBounds
+}
+
+// Leaves point to byte offset of feature in features section, other nodes
+// to index of their first child.
+func encodeFgbPackedRTree(leaves []fgbNodeItem, nodeSize int) []byte {
+	levelBounds := getFgbRTreeLevelBounds(len(leaves), nodeSize)
+	numNodes := levelBounds[0][1]
+	nodes := make([]fgbNodeItem, numNodes)
+	copy(nodes[levelBounds[0][0]:], leaves)
+
+	for i := 0; i < len(levelBounds)-1; i++ {
+		pos, end := levelBounds[i][0], levelBounds[i][1]
+		newPos := levelBounds[i+1][0]
+		for pos < end {
+			node := fgbNodeItem{envelope: newFgbEnvelope(), offset: uint64(pos)}
+			for j := 0; j < nodeSize && pos < end; j++ {
+				node.envelope.expandEnvelope(nodes[pos].envelope)
+				pos++
+			}
+			nodes[newPos] = node
+			newPos++
+		}
+	}
+
+	buf := make([]byte, 0, numNodes*40)
+	for _, node := range nodes {
+		for _, v := range node.envelope {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+		buf = binary.LittleEndian.AppendUint64(buf, node.offset)
+	}
+	return buf
+}
+
+const fgbHilbertMax = (1 << 16) - 1
+
+func getFgbHilbertValue(envelope fgbEnvelope, extent fgbEnvelope) uint32 {
+	if envelope.isEmpty() {
+		return 0
+	}
+	var x, y uint32
+	if width := extent[2] - extent[0]; width > 0 {
+		x = uint32(fgbHilbertMax * ((envelope[0]+envelope[2])/2 - extent[0]) / width)
+	}
+	if height := extent[3] - extent[1]; height > 0 {
+		y = uint32(fgbHilbertMax * ((envelope[1]+envelope[3])/2 - extent[1]) / height)
+	}
+	return hilbert(x, y)
+}
+
+// Position of x, y on hilbert curve of order 16, port of
+// https://github.com/rawrunprotected/hilbert_curves
+func hilbert(x uint32, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	i0 = (i0 | (i0 << 8)) & 0x00FF00FF
+	i0 = (i0 | (i0 << 4)) & 0x0F0F0F0F
+	i0 = (i0 | (i0 << 2)) & 0x33333333
+	i0 = (i0 | (i0 << 1)) & 0x55555555
+
+	i1 = (i1 | (i1 << 8)) & 0x00FF00FF
+	i1 = (i1 | (i1 << 4)) & 0x0F0F0F0F
+	i1 = (i1 | (i1 << 2)) & 0x33333333
+	i1 = (i1 | (i1 << 1)) & 0x55555555
+
+	return (i1 << 1) | i0
+}
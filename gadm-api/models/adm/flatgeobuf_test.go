@@ -0,0 +1,157 @@
+package adm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+func getFgbTable(data []byte) flatbuffers.Table {
+	// skip size prefix
+	buf := data[flatbuffers.SizeUint32:]
+	return flatbuffers.Table{Bytes: buf, Pos: flatbuffers.GetUOffsetT(buf)}
+}
+
+func getFgbTableField(table flatbuffers.Table, slot int) flatbuffers.UOffsetT {
+	return flatbuffers.UOffsetT(table.Offset(flatbuffers.VOffsetT(4 + 2*slot)))
+}
+
+func TestEncodeFgbFeature(t *testing.T) {
+	t.Logf("Test: encodeFgbFeature - geometry and properties")
+
+	adm := Adm{
+		ID:       "6a1f4a38-0a34-4c1c-8a6b-0c6c3a2a9f11",
+		Level:    1,
+		GeomHash: "abc",
+		Metadata: []byte(`{"gid_1": "POL.1_1", "fid": 7, "cc_1": null}`),
+		Geom:     []byte(`{"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 1], [0, 0]]]}`),
+	}
+	columns := getFgbColumns([]admMetadataKey{
+		{Name: "cc_1", Types: []string{"null", "string"}},
+		{Name: "fid", Types: []string{"integer"}},
+		{Name: "gid_1", Types: []string{"string"}},
+	})
+
+	data, envelope, err := encodeFgbFeature(flatbuffers.NewBuilder(0), adm, columns)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size := binary.LittleEndian.Uint32(data); int(size) != len(data)-4 {
+		t.Errorf("unexpected size prefix %d for feature of %d bytes", size, len(data)-4)
+	}
+	if envelope != (fgbEnvelope{0, 0, 2, 1}) {
+		t.Errorf("unexpected envelope: %v", envelope)
+	}
+
+	feature := getFgbTable(data)
+
+	var geometry flatbuffers.Table
+	geometry.Bytes = feature.Bytes
+	geometry.Pos = feature.Indirect(getFgbTableField(feature, 0) + feature.Pos)
+	if geometryType := geometry.GetByte(geometry.Pos + getFgbTableField(geometry, 6)); geometryType != fgbGeometryTypeMultiPolygon {
+		t.Errorf("unexpected geometry type: %d", geometryType)
+	}
+	if parts := geometry.VectorLen(getFgbTableField(geometry, 7)); parts != 1 {
+		t.Errorf("unexpected number of parts: %d", parts)
+	}
+
+	propertiesOffset := getFgbTableField(feature, 1)
+	properties := feature.ByteVector(propertiesOffset + feature.Pos)
+	var expected bytes.Buffer
+	writeProperty := func(column int, value any) {
+		binary.Write(&expected, binary.LittleEndian, uint16(column))
+		if s, ok := value.(string); ok {
+			binary.Write(&expected, binary.LittleEndian, uint32(len(s)))
+			expected.WriteString(s)
+			return
+		}
+		binary.Write(&expected, binary.LittleEndian, value)
+	}
+	writeProperty(0, adm.ID)
+	writeProperty(1, int32(1))
+	writeProperty(2, "abc")
+	writeProperty(4, int64(7))
+	writeProperty(5, "POL.1_1")
+	if !bytes.Equal(properties, expected.Bytes()) {
+		t.Errorf("unexpected properties: %v. Expected %v", properties, expected.Bytes())
+	}
+}
+
+func TestGetFgbRTreeLevelBounds(t *testing.T) {
+	t.Logf("Test: getFgbRTreeLevelBounds - leaves last, root first")
+
+	testCases := []struct {
+		numItems int
+		expected [][2]int
+	}{
+		{numItems: 1, expected: [][2]int{{1, 2}, {0, 1}}},
+		{numItems: 16, expected: [][2]int{{1, 17}, {0, 1}}},
+		{numItems: 17, expected: [][2]int{{3, 20}, {1, 3}, {0, 1}}},
+	}
+
+	for _, testCase := range testCases {
+		result := getFgbRTreeLevelBounds(testCase.numItems, 16)
+		if len(result) != len(testCase.expected) {
+			t.Errorf("unexpected level bounds for %d items: %v", testCase.numItems, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != testCase.expected[i] {
+				t.Errorf("unexpected level bounds for %d items: %v", testCase.numItems, result)
+				break
+			}
+		}
+	}
+}
+
+func TestWriteFgbWithIndex(t *testing.T) {
+	t.Logf("Test: writeFgbWithIndex - index root covers all features")
+
+	admCh := make(chan Adm, 3)
+	for i, geom := range []string{
+		`{"type": "MultiPolygon", "coordinates": [[[[10, 10], [11, 10], [11, 11], [10, 10]]]]}`,
+		`{"type": "MultiPolygon", "coordinates": [[[[-5, -3], [0, -3], [0, 0], [-5, -3]]]]}`,
+		`{"type": "MultiPolygon", "coordinates": [[[[1, 1], [2, 1], [2, 2], [1, 1]]]]}`,
+	} {
+		admCh <- Adm{ID: string(rune('a' + i)), Geom: []byte(geom)}
+	}
+	close(admCh)
+
+	var buf bytes.Buffer
+	header := fgbHeader{name: "adm", columns: fgbAdmColumns, srid: 4326}
+	if err := writeFgbWithIndex(context.Background(), &buf, header, admCh); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.Equal(data[:len(fgbMagicBytes)], fgbMagicBytes) {
+		t.Errorf("unexpected magic bytes: %v", data[:len(fgbMagicBytes)])
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[len(fgbMagicBytes):]))
+	indexStart := len(fgbMagicBytes) + 4 + headerSize
+
+	root := data[indexStart : indexStart+40]
+	var rootEnvelope fgbEnvelope
+	for i := range rootEnvelope {
+		rootEnvelope[i] = math.Float64frombits(binary.LittleEndian.Uint64(root[i*8:]))
+	}
+	if rootEnvelope != (fgbEnvelope{-5, -3, 11, 11}) {
+		t.Errorf("unexpected root envelope: %v", rootEnvelope)
+	}
+
+	// 3 leaves and root
+	featuresStart := indexStart + 4*40
+	firstLeaf := data[indexStart+40 : indexStart+80]
+	if offset := binary.LittleEndian.Uint64(firstLeaf[32:]); offset != 0 {
+		t.Errorf("unexpected offset of first leaf: %d", offset)
+	}
+	featureSize := int(binary.LittleEndian.Uint32(data[featuresStart:]))
+	secondLeaf := data[indexStart+80 : indexStart+120]
+	if offset := binary.LittleEndian.Uint64(secondLeaf[32:]); int(offset) != featureSize+4 {
+		t.Errorf("unexpected offset of second leaf: %d. Expected %d", offset, featureSize+4)
+	}
+}
@@ -28,7 +28,12 @@ func newFlusher(ctx context.Context, w io.Writer) (*flusher, error) {
 
 func (f *flusher) flush(data []byte) error {
 	dataWithNewline := append(data, []byte("\n")...)
-	if _, err := f.w.Write(dataWithNewline); err != nil {
+	return f.flushRaw(dataWithNewline)
+}
+
+// Writes data as is, for formats with their own record separators.
+func (f *flusher) flushRaw(data []byte) error {
+	if _, err := f.w.Write(data); err != nil {
 		return fmt.Errorf("failed_to_write_data: %w", err)
 	}
 
@@ -0,0 +1,288 @@
+package adm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// GeoParquet encoding, see https://geoparquet.org/releases/v1.1.0/
+
+const GEOPARQUET_VERSION = "1.1.0"
+const GEOPARQUET_GEOMETRY_COLUMN = "geometry"
+const GEOPARQUET_BBOX_COLUMN = "bbox"
+const GEOPARQUET_ROWS_PER_ROW_GROUP = 10000
+
+var geoparquetWkbGeometryTypes = map[uint32]string{
+	3: "Polygon",
+	6: "MultiPolygon",
+}
+
+type geoparquetColumn struct {
+	name      string
+	valueType string
+}
+
+// Columns of adm row, metadata keys with the same name are not exported.
+var geoparquetAdmColumns = []string{"id", "lv", "geom_hash", GEOPARQUET_GEOMETRY_COLUMN, GEOPARQUET_BBOX_COLUMN}
+
+func getGeoparquetColumns(metadataKeys []admMetadataKey) []geoparquetColumn {
+	columns := []geoparquetColumn{}
+	for _, key := range metadataKeys {
+		isAdmColumn := false
+		for _, admColumn := range geoparquetAdmColumns {
+			isAdmColumn = isAdmColumn || key.Name == admColumn
+		}
+		if !isAdmColumn {
+			columns = append(columns, geoparquetColumn{name: key.Name, valueType: key.valueType()})
+		}
+	}
+	return columns
+}
+
+func getGeoparquetSchema(columns []geoparquetColumn) *parquet.Schema {
+	group := parquet.Group{
+		"id":                       parquet.String(),
+		"lv":                       parquet.Int(32),
+		"geom_hash":                parquet.String(),
+		GEOPARQUET_GEOMETRY_COLUMN: parquet.Leaf(parquet.ByteArrayType),
+		GEOPARQUET_BBOX_COLUMN: parquet.Group{
+			"xmin": parquet.Leaf(parquet.DoubleType),
+			"ymin": parquet.Leaf(parquet.DoubleType),
+			"xmax": parquet.Leaf(parquet.DoubleType),
+			"ymax": parquet.Leaf(parquet.DoubleType),
+		},
+	}
+	for _, column := range columns {
+		var node parquet.Node
+		switch column.valueType {
+		case metadataValueTypeString:
+			node = parquet.String()
+		case metadataValueTypeBoolean:
+			node = parquet.Leaf(parquet.BooleanType)
+		case metadataValueTypeInteger:
+			node = parquet.Int(64)
+		case metadataValueTypeNumber:
+			node = parquet.Leaf(parquet.DoubleType)
+		default:
+			node = parquet.JSON()
+		}
+		group[column.name] = parquet.Optional(node)
+	}
+	return parquet.NewSchema("adm", group)
+}
+
+type geoparquetMetadata struct {
+	Version       string                              `json:"version"`
+	PrimaryColumn string                              `json:"primary_column"`
+	Columns       map[string]geoparquetColumnMetadata `json:"columns"`
+}
+
+// crs is omitted, which stands for OGC:CRS84
+type geoparquetColumnMetadata struct {
+	Encoding      string              `json:"encoding"`
+	GeometryTypes []string            `json:"geometry_types"`
+	Bbox          []float64           `json:"bbox,omitempty"`
+	Covering      *geoparquetCovering `json:"covering,omitempty"`
+}
+
+type geoparquetCovering struct {
+	Bbox geoparquetBboxCovering `json:"bbox"`
+}
+
+type geoparquetBboxCovering struct {
+	Xmin []string `json:"xmin"`
+	Ymin []string `json:"ymin"`
+	Xmax []string `json:"xmax"`
+	Ymax []string `json:"ymax"`
+}
+
+func newGeoparquetMetadata(geometryTypes []string, extent [4]float64) geoparquetMetadata {
+	columnMetadata := geoparquetColumnMetadata{
+		Encoding:      "WKB",
+		GeometryTypes: geometryTypes,
+		Covering: &geoparquetCovering{Bbox: geoparquetBboxCovering{
+			Xmin: []string{GEOPARQUET_BBOX_COLUMN, "xmin"},
+			Ymin: []string{GEOPARQUET_BBOX_COLUMN, "ymin"},
+			Xmax: []string{GEOPARQUET_BBOX_COLUMN, "xmax"},
+			Ymax: []string{GEOPARQUET_BBOX_COLUMN, "ymax"},
+		}},
+	}
+	if extent[0] <= extent[2] {
+		columnMetadata.Bbox = extent[:]
+	}
+	return geoparquetMetadata{
+		Version:       GEOPARQUET_VERSION,
+		PrimaryColumn: GEOPARQUET_GEOMETRY_COLUMN,
+		Columns:       map[string]geoparquetColumnMetadata{GEOPARQUET_GEOMETRY_COLUMN: columnMetadata},
+	}
+}
+
+// Writes adms queried with WKB geometry and bbox as a single GeoParquet
+// file. Metadata goes to the file footer, so the file bbox and geometry
+// types are collected while rows are written.
+func writeGeoparquet(ctx context.Context, w io.Writer, columns []geoparquetColumn, admCh <-chan Adm) error {
+	schema := getGeoparquetSchema(columns)
+	writer := parquet.NewWriter(
+		w,
+		schema,
+		parquet.Compression(&parquet.Zstd),
+		parquet.MaxRowsPerRowGroup(GEOPARQUET_ROWS_PER_ROW_GROUP),
+	)
+
+	// leaf columns of the group are sorted by path
+	columnIdxs := map[string]int{}
+	for i, path := range schema.Columns() {
+		columnIdxs[strings.Join(path, ".")] = i
+	}
+
+	// minx, miny, maxx, maxy
+	extent := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	geometryTypes := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case adm, ok := <-admCh:
+			if !ok {
+				types := make([]string, 0, len(geometryTypes))
+				for geometryType := range geometryTypes {
+					types = append(types, geometryType)
+				}
+				sort.Strings(types)
+				metadata, err := json.Marshal(newGeoparquetMetadata(types, extent))
+				if err != nil {
+					return fmt.Errorf("failed_to_marshal_geoparquet_metadata: %w", err)
+				}
+				writer.SetKeyValueMetadata("geo", string(metadata))
+				if err := writer.Close(); err != nil {
+					return fmt.Errorf("failed_to_close_geoparquet_writer: %w", err)
+				}
+				return nil
+			}
+
+			row, geometryType, err := encodeGeoparquetRow(adm, columns, columnIdxs)
+			if err != nil {
+				return err
+			}
+			if _, err := writer.WriteRows([]parquet.Row{row}); err != nil {
+				return fmt.Errorf("failed_to_write_geoparquet_row: adm_id=%s: %w", adm.ID, err)
+			}
+
+			geometryTypes[geometryType] = true
+			extent[0] = math.Min(extent[0], adm.Bbox[0])
+			extent[1] = math.Min(extent[1], adm.Bbox[1])
+			extent[2] = math.Max(extent[2], adm.Bbox[2])
+			extent[3] = math.Max(extent[3], adm.Bbox[3])
+		}
+	}
+}
+
+func encodeGeoparquetRow(adm Adm, columns []geoparquetColumn, columnIdxs map[string]int) (parquet.Row, string, error) {
+	if adm.Wkb == nil || len(adm.Bbox) != 4 {
+		return nil, "", fmt.Errorf("missing_geometry: adm_id=%s", adm.ID)
+	}
+	wkb, err := hex.DecodeString(*adm.Wkb)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed_to_decode_wkb: adm_id=%s: %w", adm.ID, err)
+	}
+	geometryType, err := getWkbGeometryType(wkb)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid_wkb: adm_id=%s: %w", adm.ID, err)
+	}
+
+	metadata := map[string]json.RawMessage{}
+	if len(adm.Metadata) > 0 {
+		if err := json.Unmarshal(adm.Metadata, &metadata); err != nil {
+			return nil, "", fmt.Errorf("failed_to_unmarshal_metadata: adm_id=%s: %w", adm.ID, err)
+		}
+	}
+
+	row := make(parquet.Row, len(columnIdxs))
+	setRequired := func(name string, value parquet.Value) {
+		idx := columnIdxs[name]
+		row[idx] = value.Level(0, 0, idx)
+	}
+	setRequired("id", parquet.ByteArrayValue([]byte(adm.ID)))
+	setRequired("lv", parquet.Int32Value(int32(adm.Level)))
+	setRequired("geom_hash", parquet.ByteArrayValue([]byte(adm.GeomHash)))
+	setRequired(GEOPARQUET_GEOMETRY_COLUMN, parquet.ByteArrayValue(wkb))
+	for i, name := range []string{"xmin", "ymin", "xmax", "ymax"} {
+		setRequired(GEOPARQUET_BBOX_COLUMN+"."+name, parquet.DoubleValue(adm.Bbox[i]))
+	}
+
+	for _, column := range columns {
+		idx := columnIdxs[column.name]
+		value, err := getGeoparquetValue(metadata[column.name], column.valueType)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed_to_encode_property: adm_id=%s key=%s: %w", adm.ID, column.name, err)
+		}
+		if value.IsNull() {
+			row[idx] = value.Level(0, 0, idx)
+		} else {
+			row[idx] = value.Level(0, 1, idx)
+		}
+	}
+	return row, geometryType, nil
+}
+
+func getGeoparquetValue(value json.RawMessage, valueType string) (parquet.Value, error) {
+	if len(value) == 0 || string(value) == "null" {
+		return parquet.NullValue(), nil
+	}
+
+	switch valueType {
+	case metadataValueTypeString:
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.ByteArrayValue([]byte(s)), nil
+	case metadataValueTypeBoolean:
+		var b bool
+		if err := json.Unmarshal(value, &b); err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.BooleanValue(b), nil
+	case metadataValueTypeInteger:
+		i, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(i), nil
+	case metadataValueTypeNumber:
+		f, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.DoubleValue(f), nil
+	default:
+		return parquet.ByteArrayValue(bytes.Clone(value)), nil
+	}
+}
+
+func getWkbGeometryType(wkb []byte) (string, error) {
+	if len(wkb) < 5 {
+		return "", fmt.Errorf("wkb_too_short: len=%d", len(wkb))
+	}
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if wkb[0] == 0 {
+		byteOrder = binary.BigEndian
+	}
+	wkbType := byteOrder.Uint32(wkb[1:5])
+	geometryType, ok := geoparquetWkbGeometryTypes[wkbType]
+	if !ok {
+		return "", fmt.Errorf("unsupported_wkb_geometry_type: type=%d", wkbType)
+	}
+	return geometryType, nil
+}
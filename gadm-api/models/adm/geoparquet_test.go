@@ -0,0 +1,101 @@
+package adm
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestWriteGeoparquet(t *testing.T) {
+	t.Logf("Test: writeGeoparquet - typed columns, bbox covering and geo metadata")
+
+	// little endian WKB polygon with single ring (0 0, 1 0, 1 1, 0 0)
+	wkb := "0103000000010000000400000000000000000000000000000000000000000000000000f03f0000000000000000000000000000f03f000000000000f03f00000000000000000000000000000000"
+	columns := getGeoparquetColumns([]admMetadataKey{
+		{Name: "name_1", Types: []string{"string"}},
+		{Name: "fid", Types: []string{"integer"}},
+		{Name: "extra", Types: []string{"string", "number"}},
+		{Name: "lv", Types: []string{"integer"}},
+	})
+	if len(columns) != 3 {
+		t.Fatalf("unexpected columns: %v", columns)
+	}
+
+	admCh := make(chan Adm, 2)
+	admCh <- Adm{ID: "a", Level: 1, GeomHash: "h1", Wkb: &wkb, Bbox: []float64{0, 0, 1, 1},
+		Metadata: []byte(`{"name_1": "Mazowieckie", "fid": 7, "extra": 1.5}`)}
+	admCh <- Adm{ID: "b", Level: 1, GeomHash: "h2", Wkb: &wkb, Bbox: []float64{-2, 0, 0.5, 3},
+		Metadata: []byte(`{"fid": null}`)}
+	close(admCh)
+
+	var buf bytes.Buffer
+	if err := writeGeoparquet(context.Background(), &buf, columns, admCh); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to open written file: %v", err)
+	}
+	if file.NumRows() != 2 {
+		t.Errorf("unexpected number of rows: %d. Expected 2", file.NumRows())
+	}
+
+	geo, ok := file.Lookup("geo")
+	if !ok {
+		t.Fatalf("missing geo metadata")
+	}
+	var metadata geoparquetMetadata
+	if err := json.Unmarshal([]byte(geo), &metadata); err != nil {
+		t.Fatalf("failed to unmarshal geo metadata: %v", err)
+	}
+	column := metadata.Columns["geometry"]
+	if metadata.Version != "1.1.0" || metadata.PrimaryColumn != "geometry" || column.Encoding != "WKB" {
+		t.Errorf("unexpected geo metadata: %s", geo)
+	}
+	if len(column.GeometryTypes) != 1 || column.GeometryTypes[0] != "Polygon" {
+		t.Errorf("unexpected geometry types: %v", column.GeometryTypes)
+	}
+	expectedBbox := []float64{-2, 0, 1, 3}
+	for i := range expectedBbox {
+		if len(column.Bbox) != 4 || column.Bbox[i] != expectedBbox[i] {
+			t.Errorf("unexpected bbox: %v. Expected %v", column.Bbox, expectedBbox)
+			break
+		}
+	}
+	if column.Covering == nil || column.Covering.Bbox.Xmin[0] != "bbox" {
+		t.Errorf("unexpected covering: %s", geo)
+	}
+
+	type row struct {
+		Id       string  `parquet:"id"`
+		Lv       int32   `parquet:"lv"`
+		Geometry []byte  `parquet:"geometry"`
+		Name1    *string `parquet:"name_1,optional"`
+		Fid      *int64  `parquet:"fid,optional"`
+		Extra    *string `parquet:"extra,optional"`
+		Bbox     struct {
+			Xmin float64 `parquet:"xmin"`
+		} `parquet:"bbox"`
+	}
+	rows, err := parquet.Read[row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read rows: %v", err)
+	}
+	if rows[0].Id != "a" || rows[0].Lv != 1 || hex.EncodeToString(rows[0].Geometry) != wkb {
+		t.Errorf("unexpected row: %+v", rows[0])
+	}
+	if rows[0].Name1 == nil || *rows[0].Name1 != "Mazowieckie" || rows[0].Fid == nil || *rows[0].Fid != 7 {
+		t.Errorf("unexpected properties: %+v", rows[0])
+	}
+	if rows[0].Extra == nil || *rows[0].Extra != "1.5" {
+		t.Errorf("unexpected json property: %+v", rows[0].Extra)
+	}
+	if rows[1].Name1 != nil || rows[1].Fid != nil || rows[1].Bbox.Xmin != -2 {
+		t.Errorf("unexpected row: %+v", rows[1])
+	}
+}
@@ -1,19 +1,33 @@
 package adm
 
 import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path"
+	"slices"
 	"strconv"
+	"strings"
 
 	"gadm-api/logger"
 	"gadm-api/utils"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	geojson "github.com/paulmach/go.geojson"
 )
 
+const MAX_LOOKUP_IDS = 1000
+const DEFAULT_SEARCH_LIMIT = 10
+const MAX_SEARCH_LIMIT = 50
+
 type Handler struct {
 	service *Service
 }
@@ -42,7 +56,14 @@ func (handler *Handler) getAdmNeighborsHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	result, err := handler.service.GetAdmNeighbors(r.Context(), admId)
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmNeighbors(r.Context(), admId, projection)
 	if err != nil {
 		logger.Error("failed_to_get_adm_neighbors %v", err)
 		http.Error(w, "internal_server_error", http.StatusInternalServerError)
@@ -70,7 +91,14 @@ func (handler *Handler) postAdmNeighborsForPointHandler(w http.ResponseWriter, r
 		return
 	}
 
-	result, err := handler.service.GetAdmNeighborsForPoint(r.Context(), point)
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmNeighborsForPoint(r.Context(), point, projection)
 	if err != nil {
 		logger.Error("failed_to_get_adm_neighbors_for_point %v", err)
 		http.Error(w, "internal_server_error", http.StatusInternalServerError)
@@ -87,7 +115,56 @@ func (handler *Handler) getAdmForPointHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	result, err := handler.service.GetAdmForPoint(r.Context(), point)
+	hierarchy, err := getBoolFromString(r.URL.Query().Get("hierarchy"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_hierarchy: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	includeGeometry, err := getBoolFromString(r.URL.Query().Get("include-geometry"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_include_geometry: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	matchOpts, err := getPointMatchOptsFromRequest(r)
+	if err != nil {
+		logger.Error("failed_to_get_point_match_opts_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	if hierarchy {
+		result, err := handler.service.GetAdmWithHierarchyForPoint(r.Context(), point, projection, matchOpts, includeGeometry)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "not_found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.Error("failed_to_get_adm_hierarchy_for_lat_lng %v", err)
+			http.Error(w, "internal_server_error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	result, err := handler.service.GetAdmForPoint(r.Context(), point, projection, matchOpts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "not_found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		logger.Error("failed_to_get_adm_for_lat_lng %v", err)
 		http.Error(w, "internal_server_error", http.StatusInternalServerError)
@@ -116,6 +193,142 @@ func getPointFromRequestBody(r *http.Request) (utils.Point, error) {
 	return utils.NewPointLngLat(geometry.Point[0], geometry.Point[1]), nil
 }
 
+func (handler *Handler) BatchReverseGeocodeHandler(w http.ResponseWriter, r *http.Request, maxPoints int) {
+	if r.Method != http.MethodPost {
+		logger.Error("method_not_allowed %s", r.Method)
+		http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	batchPoints, err := getBatchPointsFromRequestBody(r)
+	if err != nil {
+		logger.Error("failed_to_get_points_from_request_body %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	if len(batchPoints) > maxPoints {
+		logger.Error("too_many_points points=%d max=%d", len(batchPoints), maxPoints)
+		http.Error(w, fmt.Sprintf("too_many_points: max=%d", maxPoints), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	results, err := handler.service.GetAdmsForPoints(r.Context(), batchPoints, projection)
+	if err != nil {
+		logger.Error("failed_to_get_adms_for_points %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"results": results})
+}
+
+// Accepts either a MultiPoint geometry or a FeatureCollection of Point
+// features. Features with missing or non point geometries are kept so that
+// results can be reported per input in the same order.
+func getBatchPointsFromRequestBody(r *http.Request) ([]batchPoint, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_read_request_body: %w", err)
+	}
+
+	var object struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, fmt.Errorf("failed_to_unmarshal_geojson_object: %w", err)
+	}
+
+	switch object.Type {
+	case string(geojson.GeometryMultiPoint):
+		geometry, err := geojson.UnmarshalGeometry(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed_to_unmarshal_geometry: %w", err)
+		}
+		batchPoints := make([]batchPoint, len(geometry.MultiPoint))
+		for i, coords := range geometry.MultiPoint {
+			batchPoints[i] = batchPoint{point: getPointFromCoordinates(coords)}
+		}
+		return batchPoints, nil
+	case "FeatureCollection":
+		fc, err := geojson.UnmarshalFeatureCollection(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed_to_unmarshal_feature_collection: %w", err)
+		}
+		batchPoints := make([]batchPoint, len(fc.Features))
+		for i, feature := range fc.Features {
+			batchPoints[i] = batchPoint{id: feature.ID}
+			if feature.Geometry != nil && feature.Geometry.Type == geojson.GeometryPoint {
+				batchPoints[i].point = getPointFromCoordinates(feature.Geometry.Point)
+			}
+		}
+		return batchPoints, nil
+	default:
+		return nil, fmt.Errorf("invalid_geojson_type: type %s", object.Type)
+	}
+}
+
+func getPointFromCoordinates(coords []float64) *utils.Point {
+	if len(coords) < 2 {
+		return nil
+	}
+	point := utils.NewPointLngLat(coords[0], coords[1])
+	return &point
+}
+
+func getGeometryFromRequestBody(r *http.Request) (*geojson.Geometry, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_read_request_body: %w", err)
+	}
+
+	var object struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, fmt.Errorf("failed_to_unmarshal_geojson_object: %w", err)
+	}
+
+	var geometry *geojson.Geometry
+	if object.Type == "Feature" {
+		feature, err := geojson.UnmarshalFeature(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed_to_unmarshal_feature: %w", err)
+		}
+		geometry = feature.Geometry
+	} else {
+		geometry, err = geojson.UnmarshalGeometry(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed_to_unmarshal_geometry: %w", err)
+		}
+	}
+
+	if geometry == nil {
+		return nil, fmt.Errorf("missing_geometry")
+	}
+
+	switch geometry.Type {
+	case geojson.GeometryPoint,
+		geojson.GeometryMultiPoint,
+		geojson.GeometryLineString,
+		geojson.GeometryMultiLineString,
+		geojson.GeometryPolygon,
+		geojson.GeometryMultiPolygon,
+		geojson.GeometryCollection:
+		return geometry, nil
+	default:
+		return nil, fmt.Errorf("invalid_geometry_type: type %s", geometry.Type)
+	}
+}
+
 func getLevelIntFromString(level string) (*int, error) {
 	if level == "" {
 		return nil, nil
@@ -130,6 +343,61 @@ func getLevelIntFromString(level string) (*int, error) {
 	return &_lv, nil
 }
 
+func getDepthIntFromString(depth string) (*int, error) {
+	if depth == "" {
+		return nil, nil
+	}
+	_depth, err := strconv.Atoi(depth)
+	if err != nil {
+		return nil, fmt.Errorf("failed_converting_depth_to_int %v", err)
+	}
+	if _depth < 1 || _depth > MAX_ADM_TREE_DEPTH {
+		return nil, fmt.Errorf("depth_range_error: depth=%d", _depth)
+	}
+	return &_depth, nil
+}
+
+func getBoolFromString(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	_value, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed_converting_value_to_bool %v", err)
+	}
+	return _value, nil
+}
+
+// Parses fields and geometry query params, geometry falls back to
+// defaultGeometry when not given.
+func getAdmProjectionFromRequest(r *http.Request, defaultGeometry admGeometry) (admProjection, error) {
+	fields, err := getMetadataKeysFromString(r.URL.Query().Get("fields"), MAX_PROJECTED_FIELDS)
+	if err != nil {
+		return admProjection{}, fmt.Errorf("failed_parsing_query_param_fields: %w", err)
+	}
+
+	geometry, err := getAdmGeometryFromString(r.URL.Query().Get("geometry"), defaultGeometry)
+	if err != nil {
+		return admProjection{}, fmt.Errorf("failed_parsing_query_param_geometry: %w", err)
+	}
+
+	return admProjection{fields: fields, geometry: geometry}, nil
+}
+
+func getQuantizationIntFromString(value string) (int, error) {
+	if value == "" {
+		return DEFAULT_TOPOJSON_QUANTIZATION, nil
+	}
+	quantization, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed_converting_quantization_to_int %v", err)
+	}
+	if quantization < MIN_TOPOJSON_QUANTIZATION || quantization > MAX_TOPOJSON_QUANTIZATION {
+		return 0, fmt.Errorf("quantization_range_error: %d", quantization)
+	}
+	return quantization, nil
+}
+
 func getBatchSizeIntFromString(batchSize string) (*int, error) {
 	if batchSize == "" {
 		return nil, nil
@@ -141,7 +409,47 @@ func getBatchSizeIntFromString(batchSize string) (*int, error) {
 	return &_batchSize, nil
 }
 
-func (handler *Handler) GetAdmFeatureCollectionHandler(w http.ResponseWriter, r *http.Request, baseUrl url.URL) {
+func getBboxFromString(value string) (*bbox, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid_bbox_length: bbox=%s", value)
+	}
+
+	var _bbox bbox
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid_bbox_coordinate: bbox=%s: %w", value, err)
+		}
+		_bbox[i] = coord
+	}
+
+	minx, miny, maxx, maxy := _bbox[0], _bbox[1], _bbox[2], _bbox[3]
+	if minx < -180 || maxx > 180 || miny < -90 || maxy > 90 || miny > maxy {
+		return nil, fmt.Errorf("bbox_out_of_range: bbox=%s", value)
+	}
+	return &_bbox, nil
+}
+
+func getPrecisionIntFromString(precision string) (*int, error) {
+	if precision == "" {
+		return nil, nil
+	}
+	_precision, err := strconv.Atoi(precision)
+	if err != nil {
+		return nil, fmt.Errorf("failed_converting_precision_to_int %v", err)
+	}
+	if _precision < 0 || _precision > MAX_COORDINATE_PRECISION {
+		return nil, fmt.Errorf("precision_range_error: precision=%d", _precision)
+	}
+	return &_precision, nil
+}
+
+// Parses query params shared by /fc and /geojsonl.
+func (handler *Handler) getAdmQueryOptsFromRequest(r *http.Request) (admQueryOpts, error) {
 	startAfterId := r.URL.Query().Get("start-after-id")
 	startAfterFid := r.URL.Query().Get("start-after-fid")
 	batchSize := r.URL.Query().Get("batch-size")
@@ -149,45 +457,134 @@ func (handler *Handler) GetAdmFeatureCollectionHandler(w http.ResponseWriter, r
 
 	_lv, err := getLevelIntFromString(lvString)
 	if err != nil {
-		logger.Error("failed_parsing_query_param_lv: %v", err)
-		http.Error(w, "invalid_request", http.StatusBadRequest)
-		return
+		return admQueryOpts{}, fmt.Errorf("failed_parsing_query_param_lv: %w", err)
 	}
 
 	_batchSize, err := getBatchSizeIntFromString(batchSize)
 	if err != nil {
-		logger.Error("failed_parsing_query_param_batch_size: %v", err)
-		http.Error(w, "invalid_request", http.StatusBadRequest)
-		return
+		return admQueryOpts{}, fmt.Errorf("failed_parsing_query_param_batch_size: %w", err)
+	}
+
+	_bbox, err := getBboxFromString(r.URL.Query().Get("bbox"))
+	if err != nil {
+		return admQueryOpts{}, fmt.Errorf("failed_parsing_query_param_bbox: %w", err)
+	}
+
+	country := r.URL.Query().Get("country")
+	if country == "" {
+		country = r.URL.Query().Get("gid_0")
+	}
+
+	parentId := r.URL.Query().Get("parent-id")
+	if parentId != "" {
+		if _, err := uuid.Parse(parentId); err != nil {
+			return admQueryOpts{}, fmt.Errorf("failed_parsing_query_param_parent_id: %w", err)
+		}
+	}
+
+	precision, err := getPrecisionIntFromString(r.URL.Query().Get("precision"))
+	if err != nil {
+		return admQueryOpts{}, fmt.Errorf("failed_parsing_query_param_precision: %w", err)
+	}
+
+	_crs, err := parseCrs(r.URL.Query().Get("crs"))
+	if err != nil {
+		return admQueryOpts{}, err
+	}
+	_crs, err = handler.service.ResolveCrs(r.Context(), _crs)
+	if err != nil {
+		return admQueryOpts{}, err
+	}
+
+	// csv output has its own geometry param, see admCsvHandler
+	projection := admProjection{geometry: admGeometryFull}
+	if r.URL.Query().Get("format") != "csv" {
+		projection, err = getAdmProjectionFromRequest(r, admGeometryFull)
+		if err != nil {
+			return admQueryOpts{}, err
+		}
+		// fid of last adm is needed for next page
+		if startAfterFid != "" && projection.fields != nil && !slices.Contains(projection.fields, "fid") {
+			projection.fields = append(projection.fields, "fid")
+		}
 	}
 
 	optsBuilder := NewAdmQueryOptsBuilder()
 	optsBuilder.SetLvAndBatchSize(_lv, _batchSize)
 	optsBuilder.SetStartAfterId(startAfterId)
 	optsBuilder.SetStartAfterFid(startAfterFid)
-	optsBuilder.SetIncludeGeometry(true)
-	opts, err := optsBuilder.Build()
+	optsBuilder.SetProjection(projection)
+	optsBuilder.SetPrecision(precision)
+	optsBuilder.SetCrs(_crs)
+	optsBuilder.SetBbox(_bbox)
+	optsBuilder.SetCountry(strings.ToUpper(country))
+	optsBuilder.SetParentId(parentId)
+	return optsBuilder.Build()
+}
+
+func writeAdmQueryOptsError(w http.ResponseWriter, err error) {
+	logger.Error("failed_to_get_adm_query_opts_from_request %v", err)
+	if errors.Is(err, errUnsupportedCrs) {
+		http.Error(w, "unsupported_crs", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errInvalidCursor) {
+		http.Error(w, "invalid_cursor", http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "invalid_request", http.StatusBadRequest)
+}
+
+func (handler *Handler) GetAdmFeatureCollectionHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	baseUrl url.URL,
+	cursorCodec *cursorCodec,
+) {
+	opts, err := handler.getAdmQueryOptsFromRequest(r)
 	if err != nil {
-		logger.Error("failed_to_build_adm_query_opts %v", err)
-		http.Error(w, "invalid_request", http.StatusBadRequest)
+		writeAdmQueryOptsError(w, err)
+		return
+	}
+	opts, err = getAdmPageOptsFromRequest(r, opts, cursorCodec)
+	if err != nil {
+		writeAdmQueryOptsError(w, err)
 		return
 	}
 
-	result, err := handler.service.GetAdmsFc(r.Context(), opts)
+	if r.URL.Query().Get("format") == "csv" {
+		handler.admCsvHandler(w, r, opts, &baseUrl, cursorCodec)
+		return
+	}
+
+	page, err := handler.service.GetAdmsPage(r.Context(), opts, true)
 	if err != nil {
 		logger.Error("failed_to_get_adm_feature_collection %v", err)
 		http.Error(w, "internal_server_error", http.StatusInternalServerError)
 		return
 	}
-
-	lastAdm := result.Features[len(result.Features)-1]
-	nextUrl := getAdmsNextUrl(baseUrl, lastAdm, opts)
-	if nextUrl != "" {
-		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextUrl))
+	fc, err := convertAdmsToFeatureCollection(page.adms)
+	if err != nil {
+		logger.Error("failed_to_convert_adms_to_feature_collection %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+	links, err := getAdmsPageLinks(baseUrl, opts, page, cursorCodec, mediaTypeGeojson)
+	if err != nil {
+		logger.Error("failed_to_get_adms_page_links %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
 	}
 
+	response := newFeatureCollectionResponse(fc)
+	response.Links = links
+	response.NumberMatched = &page.numberMatched
+	response.NumberMatchedIsEstimate = page.numberMatchedIsEstimate
+
+	setLinkHeader(w, links)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	w.Header().Set("Content-Crs", fmt.Sprintf("<%s>", opts.crs.uri))
+	json.NewEncoder(w).Encode(response)
 }
 
 func getAdmsNextUrl(baseUrl url.URL, lastAdm *geojson.Feature, opts admQueryOpts) string {
@@ -195,53 +592,72 @@ func getAdmsNextUrl(baseUrl url.URL, lastAdm *geojson.Feature, opts admQueryOpts
 		return ""
 	}
 
-	lastAdmId := lastAdm.ID
-	lastAdmFid := lastAdm.Properties["fid"]
 	query := baseUrl.Query()
-	if opts.startAfterId != nil {
-		query.Set("start-after-id", lastAdmId.(string))
-	}
 	if opts.startAfterFid != nil {
-		query.Set("start-after-fid", lastAdmFid.(string))
+		query.Set("start-after-fid", fmt.Sprint(lastAdm.Properties["fid"]))
+	} else {
+		query.Set("start-after-id", fmt.Sprint(lastAdm.ID))
 	}
+	setAdmsQueryParams(query, opts)
+	baseUrl.RawQuery = query.Encode()
+
+	return baseUrl.String()
+}
+
+// Carries filters and output options of opts over to links of other pages.
+func setAdmsQueryParams(query url.Values, opts admQueryOpts) {
 	if opts.lv != nil {
 		query.Set("lv", fmt.Sprintf("%d", *opts.lv))
 	}
-	query.Set("batch-size", fmt.Sprintf("%d", opts.batchSize))
-	baseUrl.RawQuery = query.Encode()
+	if opts.precision != DEFAULT_COORDINATE_PRECISION {
+		query.Set("precision", fmt.Sprintf("%d", opts.precision))
+	}
+	if !opts.crs.isDefault() {
+		query.Set("crs", opts.crs.uri)
+	}
+	if opts.bbox != nil {
+		query.Set("bbox", opts.bbox.String())
+	}
+	if opts.country != nil {
+		query.Set("country", *opts.country)
+	}
+	if opts.parentId != nil {
+		query.Set("parent-id", *opts.parentId)
+	}
+	if opts.batchSize != nil {
+		query.Set("batch-size", fmt.Sprintf("%d", *opts.batchSize))
+	}
+	if opts.csvGeometry == "" {
+		if opts.projection.fields != nil {
+			query.Set("fields", strings.Join(opts.projection.fields, ","))
+		}
+		if opts.projection.geometry != admGeometryFull {
+			query.Set("geometry", string(opts.projection.geometry))
+		}
+	}
+}
 
-	return baseUrl.String()
+func setNextLinkHeader(w http.ResponseWriter, baseUrl url.URL, query url.Values) {
+	baseUrl.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", baseUrl.String()))
 }
 
-func (handler *Handler) AdmGeojsonlHandler(w http.ResponseWriter, r *http.Request) {
+func (handler *Handler) AdmGeojsonlHandler(w http.ResponseWriter, r *http.Request, baseUrl url.URL) {
 	logger.Info("geojsonl_handler_called")
-	startAfterId := r.URL.Query().Get("start-after-id")
-	startAfterFid := r.URL.Query().Get("start-after-fid")
-	batchSize := r.URL.Query().Get("batch-size")
-	lvString := r.URL.Query().Get("lv")
-
-	_lv, err := getLevelIntFromString(lvString)
+	opts, err := handler.getAdmQueryOptsFromRequest(r)
 	if err != nil {
-		logger.Error("failed_parsing_query_param_lv: %v", err)
-		http.Error(w, "invalid_request", http.StatusBadRequest)
+		writeAdmQueryOptsError(w, err)
 		return
 	}
 
-	_batchSize, err := getBatchSizeIntFromString(batchSize)
-	if err != nil {
-		logger.Error("failed_parsing_query_param_batch_size: %v", err)
-		http.Error(w, "invalid_request", http.StatusBadRequest)
+	if r.URL.Query().Get("format") == "csv" {
+		handler.admCsvHandler(w, r, opts, nil, nil)
 		return
 	}
 
-	optsBuilder := NewAdmQueryOptsBuilder()
-	optsBuilder.SetLvAndBatchSize(_lv, _batchSize)
-	optsBuilder.SetStartAfterId(startAfterId)
-	optsBuilder.SetStartAfterFid(startAfterFid)
-	optsBuilder.SetIncludeGeometry(true)
-	opts, err := optsBuilder.Build()
+	withTrailerRecord, err := getBoolFromString(r.URL.Query().Get("trailer"))
 	if err != nil {
-		logger.Error("failed_to_validate_adm_query_params %v", err)
+		logger.Error("failed_parsing_query_param_trailer: %v", err)
 		http.Error(w, "invalid_request", http.StatusBadRequest)
 		return
 	}
@@ -252,24 +668,862 @@ func (handler *Handler) AdmGeojsonlHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	ch := make(chan json.RawMessage, 10)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ch := make(chan geojsonlFeature, 10)
+	errCh := make(chan error, 1)
 	go func() {
-		err := handler.service.getAdmGeojsonlStream(r.Context(), ch, opts)
-		if err != nil {
-			logger.Error("failed_to_get_adm_geojsonl %v", err)
-			return
-		}
+		errCh <- handler.service.getAdmGeojsonlStream(ctx, ch, opts)
 	}()
 
 	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Crs", fmt.Sprintf("<%s>", opts.crs.uri))
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
+	declareStreamTrailers(w)
 
-	for admJson := range ch {
-		err := flusher.flush(admJson)
+	var lastFeature *geojson.Feature
+	featuresSent := int64(0)
+	var writeErr error
+	for feature := range ch {
+		if writeErr != nil {
+			continue
+		}
+		if writeErr = flusher.flush(feature.data); writeErr != nil {
+			cancel()
+			continue
+		}
+		lastFeature = feature.feature
+		featuresSent++
+	}
+	streamErr := <-errCh
+	if writeErr != nil {
+		logger.Error("failed_to_write_adm_geojsonl sent=%d %v", featuresSent, writeErr)
+		return
+	}
+	if streamErr != nil {
+		logger.Error("failed_to_get_adm_geojsonl sent=%d %v", featuresSent, streamErr)
+		if featuresSent == 0 {
+			http.Error(w, "internal_server_error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	trailer := newStreamTrailer(baseUrl, opts, lastFeature, featuresSent, streamErr)
+	trailer.setHttpTrailers(w)
+	if withTrailerRecord {
+		data, err := json.Marshal(trailer)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			logger.Error("failed_to_marshal_stream_trailer %v", err)
 			return
 		}
+		flusher.flush(data)
+	}
+}
+
+// Writes adms as CSV. With baseUrl a single page is written and followed by
+// next link like in /fc, without it all adms are streamed like in /geojsonl.
+func (handler *Handler) admCsvHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	opts admQueryOpts,
+	baseUrl *url.URL,
+	cursorCodec *cursorCodec,
+) {
+	columns, err := getCsvColumnsFromString(r.URL.Query().Get("columns"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_columns: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
 	}
+
+	geometry, err := getCsvGeometryFromString(r.URL.Query().Get("geometry"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_geometry: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	withBom, err := getBoolFromString(r.URL.Query().Get("bom"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_bom: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	opts.projection = admProjection{geometry: admGeometryNone}
+	opts.csvGeometry = geometry
+	// a page takes default columns from its own adms, whole metadata is
+	// queried for that
+	if len(columns) == 0 && baseUrl == nil {
+		columns, err = handler.service.GetDefaultCsvColumns(r.Context(), opts)
+		if err != nil {
+			logger.Error("failed_to_get_default_csv_columns %v", err)
+			http.Error(w, "internal_server_error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if len(columns) > 0 {
+		opts.includeArea = slices.Contains(columns, "area_sq_m")
+		opts.projection.fields = []string{}
+		for _, column := range columns {
+			if !isCsvAdmColumn(column) {
+				opts.projection.fields = append(opts.projection.fields, column)
+			}
+		}
+	}
+
+	var adms []Adm
+	if baseUrl != nil {
+		// csv has nowhere to report numberMatched
+		page, err := handler.service.GetAdmsPage(r.Context(), opts, false)
+		if err != nil {
+			logger.Error("failed_to_get_adms_csv %v", err)
+			http.Error(w, "internal_server_error", http.StatusInternalServerError)
+			return
+		}
+		adms = page.adms
+		if len(columns) == 0 {
+			columns, err = getDefaultCsvColumnsFromAdms(adms)
+			if err != nil {
+				logger.Error("failed_to_get_default_csv_columns %v", err)
+				http.Error(w, "internal_server_error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		query := url.Values{}
+		query.Set("format", "csv")
+		query.Set("geometry", string(geometry))
+		// next pages keep default columns of the first one, so that pages
+		// share the header
+		if len(columns) <= MAX_CSV_COLUMNS {
+			query.Set("columns", strings.Join(columns, ","))
+		}
+		if withBom {
+			query.Set("bom", "true")
+		}
+		pageBaseUrl := *baseUrl
+		pageBaseUrl.RawQuery = query.Encode()
+		links, err := getAdmsPageLinks(pageBaseUrl, opts, page, cursorCodec, CSV_CONTENT_TYPE)
+		if err != nil {
+			logger.Error("failed_to_get_adms_page_links %v", err)
+			http.Error(w, "internal_server_error", http.StatusInternalServerError)
+			return
+		}
+		setLinkHeader(w, links)
+	}
+
+	encoder := newAdmCsvEncoder(columns, geometry)
+
+	flusher, err := newFlusher(r.Context(), w)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", CSV_CONTENT_TYPE)
+	w.Header().Set("Content-Crs", fmt.Sprintf("<%s>", opts.crs.uri))
+	w.Header().Set("Cache-Control", "no-cache")
+	if baseUrl == nil {
+		declareStreamTrailers(w)
+	}
+
+	header, err := encoder.encodeHeader()
+	if err != nil {
+		logger.Error("failed_to_encode_csv_header %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+	// BOM lets spreadsheet applications detect UTF-8
+	if withBom {
+		header = append(append([]byte{}, utf8Bom...), header...)
+	}
+	if err := flusher.flushRaw(header); err != nil {
+		return
+	}
+
+	if baseUrl != nil {
+		for _, adm := range adms {
+			row, err := encoder.encodeAdm(adm)
+			if err != nil {
+				logger.Error("failed_to_encode_csv_row %v", err)
+				return
+			}
+			if err := flusher.flushRaw(row); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ch := make(chan []byte, 10)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- handler.service.getAdmCsvStream(ctx, ch, opts, encoder)
+	}()
+
+	rowsSent := int64(0)
+	var writeErr error
+	for row := range ch {
+		if writeErr != nil {
+			continue
+		}
+		if writeErr = flusher.flushRaw(row); writeErr != nil {
+			cancel()
+			continue
+		}
+		rowsSent++
+	}
+	if err := <-errCh; err != nil && writeErr == nil {
+		logger.Error("failed_to_get_adm_csv_stream sent=%d %v", rowsSent, err)
+		streamTrailer{Status: streamStatusError, FeaturesSent: rowsSent}.setHttpTrailers(w)
+		return
+	}
+	streamTrailer{Status: streamStatusComplete, FeaturesSent: rowsSent}.setHttpTrailers(w)
+}
+
+func (handler *Handler) AdmFlatgeobufHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := handler.getAdmQueryOptsFromRequest(r)
+	if err != nil {
+		writeAdmQueryOptsError(w, err)
+		return
+	}
+	// FlatGeobuf readers expect easting/northing order regardless of crs
+	opts.crs.flipAxes = false
+	// features are written with polygonal geometry or none
+	if opts.projection.geometry != admGeometryFull && opts.projection.geometry != admGeometryNone {
+		http.Error(w, "unsupported_geometry", http.StatusBadRequest)
+		return
+	}
+
+	withIndex, err := getBoolFromString(r.URL.Query().Get("index"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_index: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	if withIndex && !opts.isBounded() {
+		http.Error(w, "index_requires_bounded_export", http.StatusBadRequest)
+		return
+	}
+
+	columns, err := handler.service.GetFlatgeobufColumns(r.Context(), opts)
+	if err != nil {
+		logger.Error("failed_to_get_flatgeobuf_columns %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	fileName := "adm.fgb"
+	if opts.lv != nil {
+		fileName = fmt.Sprintf("adm_lv%d.fgb", *opts.lv)
+	}
+	w.Header().Set("Content-Type", "application/vnd.flatgeobuf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	w.Header().Set("Content-Crs", fmt.Sprintf("<%s>", opts.crs.uri))
+
+	bw := bufio.NewWriterSize(w, 64*1024)
+	err = handler.service.WriteAdmsFlatgeobuf(r.Context(), bw, opts, columns, withIndex)
+	if errors.Is(err, errFgbIndexFeatureLimit) {
+		// indexed export writes nothing before all features are collected
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "too_many_features_for_index", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		logger.Error("failed_to_write_flatgeobuf %v", err)
+		return
+	}
+	if err := bw.Flush(); err != nil {
+		logger.Error("failed_to_flush_flatgeobuf %v", err)
+	}
+}
+
+func (handler *Handler) AdmsForGeometryHandler(w http.ResponseWriter, r *http.Request, baseUrl url.URL) {
+	if r.Method != http.MethodPost {
+		logger.Error("method_not_allowed %s", r.Method)
+		http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	startAfterId := r.URL.Query().Get("start-after-id")
+	batchSize := r.URL.Query().Get("batch-size")
+	lvString := r.URL.Query().Get("lv")
+
+	predicate, err := getSpatialPredicateFromString(r.URL.Query().Get("predicate"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_predicate: %v", err)
+		http.Error(w, "invalid_predicate", http.StatusBadRequest)
+		return
+	}
+
+	_lv, err := getLevelIntFromString(lvString)
+	if err != nil || _lv == nil {
+		logger.Error("failed_parsing_query_param_lv: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	_batchSize, err := getBatchSizeIntFromString(batchSize)
+	if err != nil {
+		logger.Error("failed_parsing_query_param_batch_size: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	// without batch size the query has no limit
+	if _batchSize == nil {
+		defaultBatchSize := DEFAULT_BATCH_SIZE
+		_batchSize = &defaultBatchSize
+	}
+
+	geometry, err := getGeometryFromRequestBody(r)
+	if err != nil {
+		logger.Error("failed_to_get_geometry_from_request_body %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryFull)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	optsBuilder := NewAdmQueryOptsBuilder()
+	optsBuilder.SetLvAndBatchSize(_lv, _batchSize)
+	optsBuilder.SetStartAfterId(startAfterId)
+	optsBuilder.SetProjection(projection)
+	opts, err := optsBuilder.Build()
+	if err != nil {
+		logger.Error("failed_to_build_adm_query_opts %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmsForGeometryFc(r.Context(), geometry, predicate, opts)
+	if err != nil {
+		logger.Error("failed_to_get_adms_for_geometry %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	if opts.batchSize != nil && len(result.Features) == *opts.batchSize {
+		lastAdm := result.Features[len(result.Features)-1]
+		query := baseUrl.Query()
+		query.Set("lv", fmt.Sprintf("%d", *opts.lv))
+		query.Set("predicate", string(predicate))
+		query.Set("batch-size", fmt.Sprintf("%d", *opts.batchSize))
+		query.Set("start-after-id", lastAdm.ID.(string))
+		if projection.fields != nil {
+			query.Set("fields", strings.Join(projection.fields, ","))
+		}
+		if projection.geometry != admGeometryFull {
+			query.Set("geometry", string(projection.geometry))
+		}
+		setNextLinkHeader(w, baseUrl, query)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) AdmByIdHandler(w http.ResponseWriter, r *http.Request) {
+	admId := r.PathValue("id")
+	if _, err := uuid.Parse(admId); err != nil {
+		logger.Error("invalid_adm_id %s", admId)
+		http.Error(w, "invalid_adm_id", http.StatusBadRequest)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmById(r.Context(), admId, projection)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "not_found", http.StatusNotFound)
+			return
+		}
+		logger.Error("failed_to_get_adm_by_id %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) AdmStatsHandler(w http.ResponseWriter, r *http.Request) {
+	admId := r.PathValue("id")
+	if _, err := uuid.Parse(admId); err != nil {
+		logger.Error("invalid_adm_id %s", admId)
+		http.Error(w, "invalid_adm_id", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmStats(r.Context(), admId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "not_found", http.StatusNotFound)
+			return
+		}
+		logger.Error("failed_to_get_adm_stats %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) AdmBorderHandler(w http.ResponseWriter, r *http.Request) {
+	admId := r.PathValue("id")
+	neighborId := r.PathValue("neighbor_id")
+	for _, id := range []string{admId, neighborId} {
+		if _, err := uuid.Parse(id); err != nil {
+			logger.Error("invalid_adm_id %s", id)
+			http.Error(w, "invalid_adm_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if admId == neighborId {
+		http.Error(w, "same_adm_ids", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmBorder(r.Context(), admId, neighborId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "not_found", http.StatusNotFound)
+			return
+		}
+		logger.Error("failed_to_get_adm_border %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) AdmNeighborhoodHandler(w http.ResponseWriter, r *http.Request) {
+	admId := r.PathValue("id")
+	if _, err := uuid.Parse(admId); err != nil {
+		logger.Error("invalid_adm_id %s", admId)
+		http.Error(w, "invalid_adm_id", http.StatusBadRequest)
+		return
+	}
+
+	hops, err := getHopsIntFromString(r.URL.Query().Get("hops"), DEFAULT_NEIGHBORHOOD_HOPS, MAX_NEIGHBORHOOD_HOPS)
+	if err != nil {
+		logger.Error("failed_parsing_query_param_hops: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmNeighborhood(r.Context(), admId, hops, projection)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "not_found", http.StatusNotFound)
+			return
+		}
+		logger.Error("failed_to_get_adm_neighborhood %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) AdmPathHandler(w http.ResponseWriter, r *http.Request) {
+	admId := r.PathValue("id")
+	targetId := r.PathValue("target_id")
+	for _, id := range []string{admId, targetId} {
+		if _, err := uuid.Parse(id); err != nil {
+			logger.Error("invalid_adm_id %s", id)
+			http.Error(w, "invalid_adm_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	maxHops, err := getHopsIntFromString(r.URL.Query().Get("max-hops"), DEFAULT_ADM_PATH_MAX_HOPS, MAX_ADM_PATH_MAX_HOPS)
+	if err != nil {
+		logger.Error("failed_parsing_query_param_max_hops: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmPath(r.Context(), admId, targetId, maxHops, projection)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			http.Error(w, "not_found", http.StatusNotFound)
+		case errors.Is(err, errAdmPathNotFound):
+			http.Error(w, "path_not_found", http.StatusNotFound)
+		case errors.Is(err, errAdmLevelMismatch):
+			http.Error(w, "level_mismatch", http.StatusBadRequest)
+		default:
+			logger.Error("failed_to_get_adm_path %v", err)
+			http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) AdmLookupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		handler.getAdmLookupHandler(w, r)
+		return
+	}
+	if r.Method == http.MethodPost {
+		handler.postAdmLookupHandler(w, r)
+		return
+	}
+	logger.Error("method_not_allowed %s", r.Method)
+	http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+}
+
+func (handler *Handler) getAdmLookupHandler(w http.ResponseWriter, r *http.Request) {
+	idType, err := getAdmIdTypeFromString(r.URL.Query().Get("id-type"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_id_type: %v", err)
+		http.Error(w, "invalid_id_type", http.StatusBadRequest)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing_id", http.StatusBadRequest)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	results, err := handler.service.LookupAdms(r.Context(), idType, []string{id}, projection)
+	if err != nil {
+		logger.Error("failed_to_lookup_adm %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if results[0].Status == resultStatusNotFound {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	json.NewEncoder(w).Encode(results[0])
+}
+
+type admLookupRequestBody struct {
+	IdType string   `json:"id_type"`
+	Ids    []string `json:"ids"`
+}
+
+func (handler *Handler) postAdmLookupHandler(w http.ResponseWriter, r *http.Request) {
+	var body admLookupRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logger.Error("failed_to_decode_request_body %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	idType, err := getAdmIdTypeFromString(body.IdType)
+	if err != nil {
+		logger.Error("failed_parsing_id_type: %v", err)
+		http.Error(w, "invalid_id_type", http.StatusBadRequest)
+		return
+	}
+
+	if len(body.Ids) == 0 {
+		http.Error(w, "missing_ids", http.StatusBadRequest)
+		return
+	}
+	if len(body.Ids) > MAX_LOOKUP_IDS {
+		http.Error(w, fmt.Sprintf("too_many_ids: max=%d", MAX_LOOKUP_IDS), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	results, err := handler.service.LookupAdms(r.Context(), idType, body.Ids, projection)
+	if err != nil {
+		logger.Error("failed_to_lookup_adms %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"results": results})
+}
+
+func (handler *Handler) AdmParentHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	handler.admRelativesHandler(w, r, admRelationParent, baseApiPath)
+}
+
+func (handler *Handler) AdmChildrenHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	handler.admRelativesHandler(w, r, admRelationChildren, baseApiPath)
+}
+
+func (handler *Handler) AdmAncestorsHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	handler.admRelativesHandler(w, r, admRelationAncestors, baseApiPath)
+}
+
+func (handler *Handler) AdmDescendantsHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	handler.admRelativesHandler(w, r, admRelationDescendants, baseApiPath)
+}
+
+func (handler *Handler) admRelativesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	relation admRelation,
+	baseApiPath string,
+) {
+	admId := r.PathValue("id")
+	if _, err := uuid.Parse(admId); err != nil {
+		logger.Error("invalid_adm_id %s", admId)
+		http.Error(w, "invalid_adm_id", http.StatusBadRequest)
+		return
+	}
+
+	startAfterId := r.URL.Query().Get("start-after-id")
+	batchSize := r.URL.Query().Get("batch-size")
+
+	_batchSize, err := getBatchSizeIntFromString(batchSize)
+	if err != nil {
+		logger.Error("failed_parsing_query_param_batch_size: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	depth, err := getDepthIntFromString(r.URL.Query().Get("depth"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_depth: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	includeGeometry, err := getBoolFromString(r.URL.Query().Get("include-geometry"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_include_geometry: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	defaultGeometry := admGeometryNone
+	if includeGeometry {
+		defaultGeometry = admGeometryFull
+	}
+	projection, err := getAdmProjectionFromRequest(r, defaultGeometry)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	optsBuilder := NewAdmQueryOptsBuilder()
+	if _batchSize != nil {
+		optsBuilder.SetLvAndBatchSize(nil, _batchSize)
+	}
+	optsBuilder.SetStartAfterId(startAfterId)
+	optsBuilder.SetProjection(projection)
+	opts, err := optsBuilder.Build()
+	if err != nil {
+		logger.Error("failed_to_build_adm_query_opts %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmRelativesFc(r.Context(), admId, relation, depth, opts)
+	if err != nil {
+		logger.Error("failed_to_get_adm_%s %v", relation, err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	if opts.batchSize != nil && len(result.Features) == *opts.batchSize {
+		lastAdm := result.Features[len(result.Features)-1]
+		baseUrl := url.URL{Path: path.Join(baseApiPath, r.URL.Path)}
+		query := r.URL.Query()
+		query.Set("batch-size", fmt.Sprintf("%d", *opts.batchSize))
+		query.Set("start-after-id", lastAdm.ID.(string))
+		setNextLinkHeader(w, baseUrl, query)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) AdmTopojsonHandler(w http.ResponseWriter, r *http.Request) {
+	admId := r.PathValue("id")
+	if _, err := uuid.Parse(admId); err != nil {
+		logger.Error("invalid_adm_id %s", admId)
+		http.Error(w, "invalid_adm_id", http.StatusBadRequest)
+		return
+	}
+
+	_lv, err := getLevelIntFromString(r.URL.Query().Get("lv"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_lv: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	quantization, err := getQuantizationIntFromString(r.URL.Query().Get("quantization"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_quantization: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	fields, err := getMetadataKeysFromString(r.URL.Query().Get("fields"), MAX_PROJECTED_FIELDS)
+	if err != nil {
+		logger.Error("failed_parsing_query_param_fields: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := handler.service.GetAdmDescendantsTopology(r.Context(), admId, _lv, fields, quantization)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "not_found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errInvalidTopologyLevel) {
+			logger.Error("invalid_topology_level %v", err)
+			http.Error(w, "invalid_request", http.StatusBadRequest)
+			return
+		}
+		logger.Error("failed_to_get_adm_topology %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (handler *Handler) SearchAdmsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logger.Error("method_not_allowed %s", r.Method)
+		http.Error(w, "method_not_allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	searchQuery := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(searchQuery)) < 2 {
+		http.Error(w, "query_too_short", http.StatusBadRequest)
+		return
+	}
+
+	lv, err := getLevelIntFromString(r.URL.Query().Get("lv"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_lv: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := getBatchSizeIntFromString(r.URL.Query().Get("limit"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_limit: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	_limit := DEFAULT_SEARCH_LIMIT
+	if limit != nil {
+		_limit = utils.Clamp(*limit, 1, MAX_SEARCH_LIMIT)
+	}
+
+	country := strings.ToUpper(r.URL.Query().Get("country"))
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryNone)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	results, err := handler.service.SearchAdms(r.Context(), searchQuery, lv, country, _limit, projection)
+	if err != nil {
+		logger.Error("failed_to_search_adms %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"results": results})
+}
+
+// Tiles are the same for every token, so shared caches may store them. Vary
+// keeps a CDN from serving them to requests without the token they were
+// fetched with, shorter s-maxage makes it revalidate the token more often.
+const TILE_CACHE_CONTROL = "public, max-age=86400, s-maxage=3600, stale-while-revalidate=604800"
+
+func (handler *Handler) AdmTileHandler(w http.ResponseWriter, r *http.Request, zoomRanges TileLevelZoomRanges) {
+	tile, err := getTileCoordsFromStrings(r.PathValue("z"), r.PathValue("x"), r.PathValue("y"))
+	if err != nil {
+		logger.Error("failed_parsing_tile_coords: %v", err)
+		http.Error(w, "invalid_tile", http.StatusBadRequest)
+		return
+	}
+
+	mvt, err := handler.service.GetAdmTile(r.Context(), tile, zoomRanges)
+	if err != nil {
+		logger.Error("failed_to_get_adm_tile %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	hash := sha1.Sum(mvt)
+	etag := fmt.Sprintf("\"%s\"", hex.EncodeToString(hash[:]))
+	w.Header().Set("Cache-Control", TILE_CACHE_CONTROL)
+	w.Header().Add("Vary", "Authorization")
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if len(mvt) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Write(mvt)
 }
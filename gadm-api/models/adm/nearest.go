@@ -0,0 +1,123 @@
+package adm
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gadm-api/utils"
+)
+
+const (
+	admMatchContains = "contains"
+	admMatchNearest  = "nearest"
+)
+
+const DEFAULT_NEAREST_MAX_DISTANCE_M = 50_000
+const MAX_NEAREST_MAX_DISTANCE_M = 500_000
+const MAX_BORDER_TOLERANCE_M = 5_000
+
+// Index ordering with `<->` is in degrees, which stretch with latitude, so
+// a few nearest geometries of each level are taken and ranked again by
+// geodesic distance.
+const NEAREST_ADM_CANDIDATES = 8
+
+// Nested levels along the same coastline are this close to the point.
+const NEAREST_DISTANCE_EPSILON_M = 0.01
+
+// Shortest length of a degree of latitude, parallels shrink with cos(lat).
+const MIN_METERS_PER_DEGREE = 110_574
+
+// Envelope in degrees holding every location within distanceM of the point,
+// used as an index prefilter ahead of geodesic distance checks.
+func getEnvelopeAroundPoint(point utils.Point, distanceM float64) bbox {
+	latDelta := distanceM / MIN_METERS_PER_DEGREE
+	lngDelta := 180.0
+	// parallels are shortest at the edge of envelope closest to a pole
+	if maxLat := math.Abs(point.Lat) + latDelta; maxLat < 90 {
+		lngDelta = math.Min(latDelta/math.Cos(maxLat*math.Pi/180), 180)
+	}
+	return bbox{point.Lng - lngDelta, point.Lat - latDelta, point.Lng + lngDelta, point.Lat + latDelta}
+}
+
+// Zero value only matches adms containing the point.
+type pointMatchOpts struct {
+	nearest      bool
+	maxDistanceM float64
+	// reports adms of matched level within tolerance, see admBorderProximity
+	toleranceM *float64
+}
+
+// Points within tolerance of matched adm boundary may belong to a
+// neighbor, ambiguous matches list every such adm of the same level.
+type admBorderProximity struct {
+	ToleranceM        float64 `json:"tolerance_m"`
+	BoundaryDistanceM float64 `json:"boundary_distance_m"`
+	Ambiguous         bool    `json:"ambiguous"`
+	// nearest first, with distance_m from the point
+	Nearby []Adm `json:"nearby"`
+}
+
+func newAdmBorderProximity(toleranceM float64, boundaryDistanceM float64, nearby []Adm) *admBorderProximity {
+	if nearby == nil {
+		nearby = []Adm{}
+	}
+	return &admBorderProximity{
+		ToleranceM:        toleranceM,
+		BoundaryDistanceM: boundaryDistanceM,
+		Ambiguous:         len(nearby) > 0,
+		Nearby:            nearby,
+	}
+}
+
+// Parses fallback, max-distance-m and tolerance query params of reverse
+// geocoding.
+func getPointMatchOptsFromRequest(r *http.Request) (pointMatchOpts, error) {
+	toleranceM, err := getToleranceFromString(r.URL.Query().Get("tolerance"))
+	if err != nil {
+		return pointMatchOpts{}, err
+	}
+
+	fallback := r.URL.Query().Get("fallback")
+	maxDistance := r.URL.Query().Get("max-distance-m")
+
+	switch fallback {
+	case "":
+		if maxDistance != "" {
+			return pointMatchOpts{}, fmt.Errorf("max_distance_requires_fallback")
+		}
+		return pointMatchOpts{toleranceM: toleranceM}, nil
+	case admMatchNearest:
+	default:
+		return pointMatchOpts{}, fmt.Errorf("invalid_fallback: %s", fallback)
+	}
+
+	opts := pointMatchOpts{nearest: true, maxDistanceM: DEFAULT_NEAREST_MAX_DISTANCE_M, toleranceM: toleranceM}
+	if maxDistance == "" {
+		return opts, nil
+	}
+	_maxDistance, err := strconv.ParseFloat(maxDistance, 64)
+	if err != nil {
+		return pointMatchOpts{}, fmt.Errorf("failed_converting_max_distance_to_float %v", err)
+	}
+	if !(_maxDistance >= 0 && _maxDistance <= MAX_NEAREST_MAX_DISTANCE_M) {
+		return pointMatchOpts{}, fmt.Errorf("max_distance_range_error: max_distance=%s", maxDistance)
+	}
+	opts.maxDistanceM = _maxDistance
+	return opts, nil
+}
+
+func getToleranceFromString(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	tolerance, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed_converting_tolerance_to_float %v", err)
+	}
+	if !(tolerance > 0 && tolerance <= MAX_BORDER_TOLERANCE_M) {
+		return nil, fmt.Errorf("tolerance_range_error: tolerance=%s", value)
+	}
+	return &tolerance, nil
+}
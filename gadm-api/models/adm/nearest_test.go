@@ -0,0 +1,150 @@
+package adm
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"gadm-api/utils"
+)
+
+func TestGetPointMatchOptsFromRequest(t *testing.T) {
+	t.Logf("Test: getPointMatchOptsFromRequest - fallback and max distance")
+
+	cases := map[string]pointMatchOpts{
+		"/reverse-geocode":                                     {},
+		"/reverse-geocode?fallback=nearest":                    {nearest: true, maxDistanceM: DEFAULT_NEAREST_MAX_DISTANCE_M},
+		"/reverse-geocode?fallback=nearest&max-distance-m=250": {nearest: true, maxDistanceM: 250},
+		"/reverse-geocode?fallback=nearest&max-distance-m=0":   {nearest: true, maxDistanceM: 0},
+	}
+	for url, expected := range cases {
+		opts, err := getPointMatchOptsFromRequest(httptest.NewRequest("POST", url, nil))
+		if err != nil || opts != expected {
+			t.Errorf("unexpected opts for %s: %+v %v. Expected %+v", url, opts, err, expected)
+		}
+	}
+
+	opts, err := getPointMatchOptsFromRequest(httptest.NewRequest("POST", "/reverse-geocode?tolerance=25&fallback=nearest", nil))
+	if err != nil || !opts.nearest || opts.toleranceM == nil || *opts.toleranceM != 25 {
+		t.Errorf("unexpected opts with tolerance: %+v %v", opts, err)
+	}
+
+	for _, url := range []string{
+		"/reverse-geocode?tolerance=0",
+		"/reverse-geocode?tolerance=10000",
+		"/reverse-geocode?tolerance=ten",
+		"/reverse-geocode?fallback=closest",
+		"/reverse-geocode?max-distance-m=250",
+		"/reverse-geocode?fallback=nearest&max-distance-m=-1",
+		"/reverse-geocode?fallback=nearest&max-distance-m=NaN",
+		"/reverse-geocode?fallback=nearest&max-distance-m=1000000",
+	} {
+		if _, err := getPointMatchOptsFromRequest(httptest.NewRequest("POST", url, nil)); err == nil {
+			t.Errorf("expected error for %s", url)
+		}
+	}
+}
+
+func TestGetNearestAdmForPointSqlQuery(t *testing.T) {
+	t.Logf("Test: getNearestAdmForPointSqlQuery - point, candidates per level and max distance args")
+
+	_, args, err := getNearestAdmForPointSqlQuery(utils.NewPointLngLat(18.6, 54.5), 1000, admProjection{})
+	if err != nil {
+		t.Fatalf("failed to build query: %v", err)
+	}
+
+	expected := []any{18.6, 54.5, NEAREST_ADM_CANDIDATES, 1000.0, NEAREST_DISTANCE_EPSILON_M}
+	if !slices.Equal(args, expected) {
+		t.Errorf("unexpected args: %v. Expected %v", args, expected)
+	}
+}
+
+func TestNewAdmBorderProximity(t *testing.T) {
+	t.Logf("Test: newAdmBorderProximity - ambiguity and json of nearby adms")
+
+	type proximityJson struct {
+		ToleranceM        float64 `json:"tolerance_m"`
+		BoundaryDistanceM float64 `json:"boundary_distance_m"`
+		Ambiguous         bool    `json:"ambiguous"`
+		Nearby            []struct {
+			ID        string   `json:"id"`
+			DistanceM *float64 `json:"distance_m"`
+		} `json:"nearby"`
+	}
+
+	var decoded proximityJson
+	data, _ := json.Marshal(newAdmBorderProximity(25, 40, nil))
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode proximity: %v", err)
+	}
+	if decoded.ToleranceM != 25 || decoded.BoundaryDistanceM != 40 || decoded.Ambiguous || decoded.Nearby == nil || len(decoded.Nearby) != 0 {
+		t.Errorf("expected unambiguous proximity with empty nearby list: %s", data)
+	}
+
+	distanceM := 12.5
+	decoded = proximityJson{}
+	data, _ = json.Marshal(newAdmBorderProximity(25, 3, []Adm{{ID: "neighbor", DistanceM: &distanceM}}))
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode proximity: %v", err)
+	}
+	if !decoded.Ambiguous || len(decoded.Nearby) != 1 || decoded.Nearby[0].ID != "neighbor" ||
+		decoded.Nearby[0].DistanceM == nil || *decoded.Nearby[0].DistanceM != distanceM {
+		t.Errorf("expected ambiguous proximity with neighbor: %s", data)
+	}
+}
+
+func TestGetAdmsNearPointSqlQuery(t *testing.T) {
+	t.Logf("Test: getAdmsNearPointSqlQuery - every adm within tolerance envelope, no candidate cap")
+
+	point := utils.NewPointLngLat(18.6, 54.5)
+	sql, args, err := getAdmsNearPointSqlQuery(point, 5, "matched-id", MAX_BORDER_TOLERANCE_M, admProjection{})
+	if err != nil {
+		t.Fatalf("failed to build query: %v", err)
+	}
+
+	envelope := getEnvelopeAroundPoint(point, MAX_BORDER_TOLERANCE_M)
+	expected := []any{18.6, 54.5, 5, "matched-id", envelope[0], envelope[1], envelope[2], envelope[3], float64(MAX_BORDER_TOLERANCE_M)}
+	if !slices.Equal(args, expected) {
+		t.Errorf("unexpected args: %v. Expected %v", args, expected)
+	}
+	// dense areas can have any number of adms within tolerance
+	if strings.Contains(sql, "LIMIT") {
+		t.Errorf("expected no limit on nearby adms: %s", sql)
+	}
+}
+
+// Destination on a sphere, which has longer degrees than the ellipsoid minimum.
+func getTestDestination(point utils.Point, distanceM float64, bearingDeg float64) utils.Point {
+	const earthRadiusM = 6_371_008.8
+	lat, lng, bearing := point.Lat*math.Pi/180, point.Lng*math.Pi/180, bearingDeg*math.Pi/180
+	angle := distanceM / earthRadiusM
+	destLat := math.Asin(math.Sin(lat)*math.Cos(angle) + math.Cos(lat)*math.Sin(angle)*math.Cos(bearing))
+	destLng := lng + math.Atan2(math.Sin(bearing)*math.Sin(angle)*math.Cos(lat), math.Cos(angle)-math.Sin(lat)*math.Sin(destLat))
+	return utils.NewPointLngLat(destLng*180/math.Pi, destLat*180/math.Pi)
+}
+
+func TestGetEnvelopeAroundPoint(t *testing.T) {
+	t.Logf("Test: getEnvelopeAroundPoint - covers tolerance at any latitude")
+
+	for _, point := range []utils.Point{
+		utils.NewPointLngLat(0, 0),
+		utils.NewPointLngLat(18.6, 54.5),
+		utils.NewPointLngLat(-70, -75),
+		utils.NewPointLngLat(25, 89.9),
+	} {
+		envelope := getEnvelopeAroundPoint(point, MAX_BORDER_TOLERANCE_M)
+		for bearing := 0.0; bearing < 360; bearing += 15 {
+			dest := getTestDestination(point, MAX_BORDER_TOLERANCE_M, bearing)
+			if dest.Lng < envelope[0] || dest.Lat < envelope[1] || dest.Lng > envelope[2] || dest.Lat > envelope[3] {
+				t.Errorf("envelope %v of %v misses %v at bearing %v", envelope, point, dest, bearing)
+			}
+		}
+	}
+
+	if envelope := getEnvelopeAroundPoint(utils.NewPointLngLat(25, 89.99), MAX_BORDER_TOLERANCE_M); envelope[2]-envelope[0] != 360 {
+		t.Errorf("expected full longitude range near pole: %v", envelope)
+	}
+}
@@ -0,0 +1,148 @@
+package adm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+const DEFAULT_NEIGHBORHOOD_HOPS = 1
+const MAX_NEIGHBORHOOD_HOPS = 5
+const DEFAULT_ADM_PATH_MAX_HOPS = 20
+const MAX_ADM_PATH_MAX_HOPS = 50
+
+var errAdmPathNotFound = errors.New("path_not_found")
+var errAdmLevelMismatch = errors.New("level_mismatch")
+
+// Neighbors of each of given adms. Graph is traversed one hop per call, so
+// that only the visited part of adm_neighbors is read.
+type admNeighborsFunc func(ctx context.Context, ids []string) (map[string][]string, error)
+
+// Hop distance of every adm within maxHops borders of start, start itself
+// excluded.
+func getAdmNeighborhoodHops(
+	ctx context.Context,
+	neighbors admNeighborsFunc,
+	startId string,
+	maxHops int,
+) (map[string]int, error) {
+	hops := map[string]int{startId: 0}
+	frontier := []string{startId}
+	for hop := 1; hop <= maxHops && len(frontier) > 0; hop++ {
+		adjacency, err := neighbors(ctx, frontier)
+		if err != nil {
+			return nil, err
+		}
+		next := []string{}
+		for _, id := range frontier {
+			for _, neighborId := range adjacency[id] {
+				if _, ok := hops[neighborId]; ok {
+					continue
+				}
+				hops[neighborId] = hop
+				next = append(next, neighborId)
+			}
+		}
+		frontier = next
+	}
+	delete(hops, startId)
+	return hops, nil
+}
+
+type admSearchSide struct {
+	// previous adm on the path towards the side's end
+	parents  map[string]string
+	depths   map[string]int
+	frontier []string
+}
+
+func newAdmSearchSide(id string) *admSearchSide {
+	return &admSearchSide{
+		parents:  map[string]string{id: ""},
+		depths:   map[string]int{id: 0},
+		frontier: []string{id},
+	}
+}
+
+// Walks parents from id to the side's end.
+func (side *admSearchSide) pathFrom(id string) []string {
+	path := []string{}
+	for ; id != ""; id = side.parents[id] {
+		path = append(path, id)
+	}
+	return path
+}
+
+// Ids of adms on the shortest path from start to target, both included.
+// Breadth-first search runs from both ends, each step expands the smaller
+// frontier by one hop until they meet.
+func getAdmShortestPath(
+	ctx context.Context,
+	neighbors admNeighborsFunc,
+	startId string,
+	targetId string,
+	maxHops int,
+) ([]string, error) {
+	if startId == targetId {
+		return []string{startId}, nil
+	}
+
+	forward, backward := newAdmSearchSide(startId), newAdmSearchSide(targetId)
+	for step := 0; step < maxHops; step++ {
+		side, other := forward, backward
+		if len(backward.frontier) < len(forward.frontier) {
+			side, other = backward, forward
+		}
+		if len(side.frontier) == 0 {
+			break
+		}
+
+		adjacency, err := neighbors(ctx, side.frontier)
+		if err != nil {
+			return nil, err
+		}
+		next := []string{}
+		meeting, meetingHops := "", 0
+		for _, id := range side.frontier {
+			for _, neighborId := range adjacency[id] {
+				if _, ok := side.depths[neighborId]; ok {
+					continue
+				}
+				side.parents[neighborId] = id
+				side.depths[neighborId] = side.depths[id] + 1
+				next = append(next, neighborId)
+
+				// whole hop is expanded, meetings on it may differ in length
+				if otherDepth, ok := other.depths[neighborId]; ok {
+					hops := side.depths[neighborId] + otherDepth
+					if meeting == "" || hops < meetingHops {
+						meeting, meetingHops = neighborId, hops
+					}
+				}
+			}
+		}
+		if meeting != "" {
+			path := forward.pathFrom(meeting)
+			slices.Reverse(path)
+			return append(path, backward.pathFrom(meeting)[1:]...), nil
+		}
+		side.frontier = next
+	}
+	return nil, errAdmPathNotFound
+}
+
+func getHopsIntFromString(value string, defaultHops int, maxHops int) (int, error) {
+	if value == "" {
+		return defaultHops, nil
+	}
+	hops, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed_converting_hops_to_int %v", err)
+	}
+	if hops < 1 || hops > maxHops {
+		return 0, fmt.Errorf("hops_range_error: hops=%d", hops)
+	}
+	return hops, nil
+}
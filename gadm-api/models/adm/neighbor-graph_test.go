@@ -0,0 +1,116 @@
+package adm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// Undirected graph given as edges, counts neighbor lookups.
+type testAdmGraph struct {
+	adjacency map[string][]string
+	calls     int
+}
+
+func newTestAdmGraph(edges ...[2]string) *testAdmGraph {
+	graph := &testAdmGraph{adjacency: map[string][]string{}}
+	for _, edge := range edges {
+		graph.adjacency[edge[0]] = append(graph.adjacency[edge[0]], edge[1])
+		graph.adjacency[edge[1]] = append(graph.adjacency[edge[1]], edge[0])
+	}
+	return graph
+}
+
+func (graph *testAdmGraph) neighbors(ctx context.Context, ids []string) (map[string][]string, error) {
+	graph.calls++
+	result := map[string][]string{}
+	for _, id := range ids {
+		result[id] = graph.adjacency[id]
+	}
+	return result, nil
+}
+
+// Cycle a-b-c-d-e-g-f-a and separate component x-y.
+func getTestAdmGraph() *testAdmGraph {
+	return newTestAdmGraph(
+		[2]string{"a", "b"}, [2]string{"b", "c"}, [2]string{"c", "d"}, [2]string{"d", "e"},
+		[2]string{"a", "f"}, [2]string{"f", "g"}, [2]string{"g", "e"},
+		[2]string{"x", "y"},
+	)
+}
+
+func TestGetAdmNeighborhoodHops(t *testing.T) {
+	t.Logf("Test: getAdmNeighborhoodHops - hop distances up to max hops")
+
+	hops, err := getAdmNeighborhoodHops(context.Background(), getTestAdmGraph().neighbors, "a", 2)
+	if err != nil {
+		t.Fatalf("failed to get neighborhood: %v", err)
+	}
+	expected := map[string]int{"b": 1, "f": 1, "c": 2, "g": 2}
+	if len(hops) != len(expected) {
+		t.Errorf("unexpected neighborhood: %v. Expected %v", hops, expected)
+	}
+	for id, hop := range expected {
+		if hops[id] != hop {
+			t.Errorf("unexpected hops of %s: %d. Expected %d", id, hops[id], hop)
+		}
+	}
+
+	graph := getTestAdmGraph()
+	hops, _ = getAdmNeighborhoodHops(context.Background(), graph.neighbors, "x", 5)
+	if len(hops) != 1 || hops["y"] != 1 || graph.calls != 2 {
+		t.Errorf("expected traversal to stop on exhausted component: %v calls=%d", hops, graph.calls)
+	}
+}
+
+func TestGetAdmShortestPath(t *testing.T) {
+	t.Logf("Test: getAdmShortestPath - bidirectional search, hop limit and unreachable adms")
+
+	cases := []struct {
+		start    string
+		target   string
+		maxHops  int
+		expected []string
+	}{
+		{"a", "a", 1, []string{"a"}},
+		{"a", "b", 1, []string{"a", "b"}},
+		{"a", "e", 3, []string{"a", "f", "g", "e"}},
+		{"e", "a", 3, []string{"e", "g", "f", "a"}},
+		{"b", "d", 5, []string{"b", "c", "d"}},
+		{"c", "f", 5, []string{"c", "b", "a", "f"}},
+	}
+	for _, c := range cases {
+		path, err := getAdmShortestPath(context.Background(), getTestAdmGraph().neighbors, c.start, c.target, c.maxHops)
+		if err != nil || !slices.Equal(path, c.expected) {
+			t.Errorf("unexpected path from %s to %s: %v %v. Expected %v", c.start, c.target, path, err, c.expected)
+		}
+	}
+
+	for _, c := range []struct {
+		start   string
+		target  string
+		maxHops int
+	}{{"a", "e", 2}, {"a", "x", 10}} {
+		_, err := getAdmShortestPath(context.Background(), getTestAdmGraph().neighbors, c.start, c.target, c.maxHops)
+		if !errors.Is(err, errAdmPathNotFound) {
+			t.Errorf("expected path not found from %s to %s, got %v", c.start, c.target, err)
+		}
+	}
+}
+
+func TestGetHopsIntFromString(t *testing.T) {
+	t.Logf("Test: getHopsIntFromString - default and range")
+
+	if hops, err := getHopsIntFromString("", 1, 5); err != nil || hops != 1 {
+		t.Errorf("expected default hops, got %d %v", hops, err)
+	}
+	if hops, err := getHopsIntFromString("5", 1, 5); err != nil || hops != 5 {
+		t.Errorf("expected 5 hops, got %d %v", hops, err)
+	}
+	for _, value := range []string{"0", "6", "two"} {
+		if _, err := getHopsIntFromString(value, 1, 5); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
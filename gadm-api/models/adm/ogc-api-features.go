@@ -0,0 +1,387 @@
+package adm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"gadm-api/logger"
+	"gadm-api/utils"
+
+	"github.com/google/uuid"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// OGC API - Features - Part 1: Core
+// https://docs.ogc.org/is/17-069r4/17-069r4.html
+
+const OGC_API_PATH = "/ogc"
+const OGC_DEFAULT_LIMIT = 10
+
+const (
+	mediaTypeJson    = "application/json"
+	mediaTypeGeojson = "application/geo+json"
+	mediaTypeHtml    = "text/html"
+)
+
+var ogcConformanceClasses = []string{
+	"http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/geojson",
+}
+
+type link struct {
+	Href  string `json:"href"`
+	Rel   string `json:"rel"`
+	Type  string `json:"type,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+type featureCollectionResponse struct {
+	Type          string             `json:"type"`
+	Features      []*geojson.Feature `json:"features"`
+	Links         []link             `json:"links,omitempty"`
+	TimeStamp     string             `json:"timeStamp,omitempty"`
+	NumberMatched *int64             `json:"numberMatched,omitempty"`
+	// numberMatched of unfiltered levels comes from query planner
+	NumberMatchedIsEstimate bool `json:"numberMatchedIsEstimate,omitempty"`
+	NumberReturned          int  `json:"numberReturned"`
+}
+
+func newFeatureCollectionResponse(fc *geojson.FeatureCollection) featureCollectionResponse {
+	features := fc.Features
+	if features == nil {
+		features = []*geojson.Feature{}
+	}
+	return featureCollectionResponse{
+		Type:           "FeatureCollection",
+		Features:       features,
+		TimeStamp:      time.Now().UTC().Format(time.RFC3339),
+		NumberReturned: len(features),
+	}
+}
+
+type ogcCollection struct {
+	Id          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	ItemType    string   `json:"itemType"`
+	Crs         []string `json:"crs"`
+	Extent      struct {
+		Spatial struct {
+			Bbox [][]float64 `json:"bbox"`
+			Crs  string      `json:"crs"`
+		} `json:"spatial"`
+	} `json:"extent"`
+	Links []link `json:"links"`
+}
+
+// Every GADM level is exposed as a separate collection named lv<N>.
+func getLevelFromCollectionId(collectionId string) (int, error) {
+	if !strings.HasPrefix(collectionId, "lv") {
+		return 0, fmt.Errorf("invalid_collection_id: %s", collectionId)
+	}
+	lv, err := getLevelIntFromString(strings.TrimPrefix(collectionId, "lv"))
+	if err != nil || lv == nil {
+		return 0, fmt.Errorf("invalid_collection_id: %s", collectionId)
+	}
+	return *lv, nil
+}
+
+// Links have to be absolute so that clients can follow them directly.
+func getOgcBaseUrl(r *http.Request, baseApiPath string) url.URL {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwardedProto := r.Header.Get("X-Forwarded-Proto"); forwardedProto != "" {
+		scheme = forwardedProto
+	}
+	return url.URL{
+		Scheme: scheme,
+		Host:   r.Host,
+		Path:   path.Join(baseApiPath, OGC_API_PATH),
+	}
+}
+
+func joinUrlPath(baseUrl url.URL, elem ...string) string {
+	baseUrl.Path = path.Join(append([]string{baseUrl.Path}, elem...)...)
+	return baseUrl.String()
+}
+
+func getOgcCollection(baseUrl url.URL, lv int) ogcCollection {
+	collectionId := fmt.Sprintf("lv%d", lv)
+	description := fmt.Sprintf(
+		"Administrative areas of GADM level %d. Pages hold at most %d items, larger limit values are reduced to it.",
+		lv,
+		getMaxBatchSize(&lv),
+	)
+	collection := ogcCollection{
+		Id:          collectionId,
+		Title:       fmt.Sprintf("GADM level %d", lv),
+		Description: description,
+		ItemType:    "feature",
+		Crs:         []string{CRS84_URI},
+		Links: []link{
+			{
+				Href: joinUrlPath(baseUrl, "collections", collectionId),
+				Rel:  "self",
+				Type: mediaTypeJson,
+			},
+			{
+				Href:  joinUrlPath(baseUrl, "collections", collectionId, "items"),
+				Rel:   "items",
+				Type:  mediaTypeGeojson,
+				Title: fmt.Sprintf("GADM level %d features", lv),
+			},
+		},
+	}
+	collection.Extent.Spatial.Bbox = [][]float64{{-180, -90, 180, 90}}
+	collection.Extent.Spatial.Crs = CRS84_URI
+	return collection
+}
+
+func writeJson(w http.ResponseWriter, mediaType string, v any) {
+	w.Header().Set("Content-Type", mediaType)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (handler *Handler) OgcLandingPageHandler(w http.ResponseWriter, r *http.Request, baseApiPath string, docsUrl string) {
+	baseUrl := getOgcBaseUrl(r, baseApiPath)
+	writeJson(w, mediaTypeJson, map[string]any{
+		"title":       "GADM API",
+		"description": "Administrative boundaries of the GADM dataset",
+		"links": []link{
+			{Href: baseUrl.String(), Rel: "self", Type: mediaTypeJson, Title: "This document"},
+			{Href: docsUrl, Rel: "service-doc", Type: mediaTypeHtml, Title: "API documentation"},
+			{Href: joinUrlPath(baseUrl, "conformance"), Rel: "conformance", Type: mediaTypeJson},
+			{Href: joinUrlPath(baseUrl, "collections"), Rel: "data", Type: mediaTypeJson},
+		},
+	})
+}
+
+func (handler *Handler) OgcConformanceHandler(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, mediaTypeJson, map[string]any{"conformsTo": ogcConformanceClasses})
+}
+
+func (handler *Handler) OgcCollectionsHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	baseUrl := getOgcBaseUrl(r, baseApiPath)
+	collections := make([]ogcCollection, 0, 6)
+	for lv := 0; lv <= 5; lv++ {
+		collections = append(collections, getOgcCollection(baseUrl, lv))
+	}
+
+	writeJson(w, mediaTypeJson, map[string]any{
+		"links": []link{
+			{Href: joinUrlPath(baseUrl, "collections"), Rel: "self", Type: mediaTypeJson},
+		},
+		"collections": collections,
+	})
+}
+
+func (handler *Handler) OgcCollectionHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	lv, err := getLevelFromCollectionId(r.PathValue("collectionId"))
+	if err != nil {
+		http.Error(w, "collection_not_found", http.StatusNotFound)
+		return
+	}
+
+	writeJson(w, mediaTypeJson, getOgcCollection(getOgcBaseUrl(r, baseApiPath), lv))
+}
+
+func (handler *Handler) OgcItemsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	baseApiPath string,
+	cursorCodec *cursorCodec,
+) {
+	collectionId := r.PathValue("collectionId")
+	lv, err := getLevelFromCollectionId(collectionId)
+	if err != nil {
+		http.Error(w, "collection_not_found", http.StatusNotFound)
+		return
+	}
+
+	limit := OGC_DEFAULT_LIMIT
+	_limit, err := getBatchSizeIntFromString(r.URL.Query().Get("limit"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_limit: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	if _limit != nil {
+		limit = *_limit
+	}
+	// per level maximum is advertised in the collection description
+	limit = utils.Clamp(limit, 1, getMaxBatchSize(&lv))
+
+	_bbox, err := getBboxFromString(r.URL.Query().Get("bbox"))
+	if err != nil {
+		logger.Error("failed_parsing_query_param_bbox: %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	startAfterId := r.URL.Query().Get("start-after-id")
+	if startAfterId != "" {
+		if _, err := uuid.Parse(startAfterId); err != nil {
+			http.Error(w, "invalid_request", http.StatusBadRequest)
+			return
+		}
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryFull)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	optsBuilder := NewAdmQueryOptsBuilder()
+	optsBuilder.SetLvAndBatchSize(&lv, &limit)
+	optsBuilder.SetStartAfterId(startAfterId)
+	optsBuilder.SetBbox(_bbox)
+	optsBuilder.SetProjection(projection)
+	opts, err := optsBuilder.Build()
+	if err != nil {
+		logger.Error("failed_to_build_adm_query_opts %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	opts, err = getAdmPageOptsFromRequest(r, opts, cursorCodec)
+	if err != nil {
+		writeAdmQueryOptsError(w, err)
+		return
+	}
+
+	page, err := handler.service.GetAdmsPage(r.Context(), opts, true)
+	if err != nil {
+		logger.Error("failed_to_get_ogc_items %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+	fc, err := convertAdmsToFeatureCollection(page.adms)
+	if err != nil {
+		logger.Error("failed_to_convert_adms_to_feature_collection %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	baseUrl := getOgcBaseUrl(r, baseApiPath)
+	itemsUrl, _ := url.Parse(joinUrlPath(baseUrl, "collections", collectionId, "items"))
+
+	selfUrl := *itemsUrl
+	selfUrl.RawQuery = r.URL.RawQuery
+	response := newFeatureCollectionResponse(fc)
+	response.NumberMatched = &page.numberMatched
+	response.NumberMatchedIsEstimate = page.numberMatchedIsEstimate
+	response.Links = []link{
+		{Href: selfUrl.String(), Rel: "self", Type: mediaTypeGeojson},
+		{Href: joinUrlPath(baseUrl, "collections", collectionId), Rel: "collection", Type: mediaTypeJson},
+	}
+
+	for _, boundary := range []struct {
+		keyset *admKeyset
+		rel    string
+	}{{page.next, "next"}, {page.prev, "prev"}} {
+		if boundary.keyset == nil {
+			continue
+		}
+		cursor, err := cursorCodec.encode(*boundary.keyset, opts)
+		if err != nil {
+			logger.Error("failed_to_encode_cursor %v", err)
+			http.Error(w, "internal_server_error", http.StatusInternalServerError)
+			return
+		}
+		query := url.Values{}
+		query.Set("limit", fmt.Sprintf("%d", *opts.batchSize))
+		query.Set("cursor", cursor)
+		if !opts.sort.isDefault() {
+			query.Set("sort", opts.sort.String())
+		}
+		if opts.bbox != nil {
+			query.Set("bbox", opts.bbox.String())
+		}
+		if projection.fields != nil {
+			query.Set("fields", strings.Join(projection.fields, ","))
+		}
+		if projection.geometry != admGeometryFull {
+			query.Set("geometry", string(projection.geometry))
+		}
+		pageUrl := *itemsUrl
+		pageUrl.RawQuery = query.Encode()
+		response.Links = append(response.Links, link{Href: pageUrl.String(), Rel: boundary.rel, Type: mediaTypeGeojson})
+	}
+
+	w.Header().Set("Content-Crs", fmt.Sprintf("<%s>", CRS84_URI))
+	writeJson(w, mediaTypeGeojson, response)
+}
+
+func (handler *Handler) OgcItemHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	collectionId := r.PathValue("collectionId")
+	lv, err := getLevelFromCollectionId(collectionId)
+	if err != nil {
+		http.Error(w, "collection_not_found", http.StatusNotFound)
+		return
+	}
+
+	featureId := r.PathValue("featureId")
+	if _, err := uuid.Parse(featureId); err != nil {
+		http.Error(w, "feature_not_found", http.StatusNotFound)
+		return
+	}
+
+	projection, err := getAdmProjectionFromRequest(r, admGeometryFull)
+	if err != nil {
+		logger.Error("failed_to_get_adm_projection_from_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	optsBuilder := NewAdmQueryOptsBuilder()
+	optsBuilder.SetLvAndBatchSize(&lv, nil)
+	optsBuilder.SetId(featureId)
+	optsBuilder.SetProjection(projection)
+	opts, err := optsBuilder.Build()
+	if err != nil {
+		logger.Error("failed_to_build_adm_query_opts %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	fc, err := handler.service.GetAdmsFc(r.Context(), opts)
+	if err != nil {
+		logger.Error("failed_to_get_ogc_item %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+	if len(fc.Features) == 0 {
+		http.Error(w, "feature_not_found", http.StatusNotFound)
+		return
+	}
+
+	featureJson, err := json.Marshal(fc.Features[0])
+	if err != nil {
+		logger.Error("failed_to_marshal_feature %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+	var feature map[string]any
+	if err := json.Unmarshal(featureJson, &feature); err != nil {
+		logger.Error("failed_to_unmarshal_feature %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	baseUrl := getOgcBaseUrl(r, baseApiPath)
+	feature["links"] = []link{
+		{Href: joinUrlPath(baseUrl, "collections", collectionId, "items", featureId), Rel: "self", Type: mediaTypeGeojson},
+		{Href: joinUrlPath(baseUrl, "collections", collectionId), Rel: "collection", Type: mediaTypeJson},
+	}
+
+	w.Header().Set("Content-Crs", fmt.Sprintf("<%s>", CRS84_URI))
+	writeJson(w, mediaTypeGeojson, feature)
+}
@@ -0,0 +1,97 @@
+package adm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetLevelFromCollectionId(t *testing.T) {
+	t.Logf("Test: getLevelFromCollectionId - lv prefix and level range")
+
+	if lv, err := getLevelFromCollectionId("lv3"); err != nil || lv != 3 {
+		t.Errorf("expected level 3, got %d %v", lv, err)
+	}
+	for _, collectionId := range []string{"", "lv", "lv6", "lv-1", "3", "level3"} {
+		if _, err := getLevelFromCollectionId(collectionId); err == nil {
+			t.Errorf("expected error for %q", collectionId)
+		}
+	}
+}
+
+func TestOgcCollectionsHandler(t *testing.T) {
+	t.Logf("Test: OgcCollectionsHandler - absolute links and advertised page size")
+
+	request := httptest.NewRequest("GET", "http://gadm.test/v1/ogc/collections", nil)
+	request.Header.Set("X-Forwarded-Proto", "https")
+	recorder := httptest.NewRecorder()
+	(&Handler{}).OgcCollectionsHandler(recorder, request, "/v1")
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != mediaTypeJson {
+		t.Errorf("unexpected content type: %s", contentType)
+	}
+	var response struct {
+		Collections []ogcCollection `json:"collections"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode collections: %v", err)
+	}
+	if len(response.Collections) != 6 {
+		t.Fatalf("unexpected number of collections: %d", len(response.Collections))
+	}
+
+	collection := response.Collections[0]
+	if collection.Id != "lv0" || collection.Links[1].Href != "https://gadm.test/v1/ogc/collections/lv0/items" {
+		t.Errorf("unexpected collection: %+v", collection)
+	}
+	if !strings.Contains(collection.Description, "at most 5 items") {
+		t.Errorf("expected page size in description: %s", collection.Description)
+	}
+	if !strings.Contains(response.Collections[5].Description, "at most 50 items") {
+		t.Errorf("expected page size in description: %s", response.Collections[5].Description)
+	}
+}
+
+func TestOgcConformanceHandler(t *testing.T) {
+	t.Logf("Test: OgcConformanceHandler - core and geojson classes")
+
+	recorder := httptest.NewRecorder()
+	(&Handler{}).OgcConformanceHandler(recorder, httptest.NewRequest("GET", "/ogc/conformance", nil))
+
+	var response struct {
+		ConformsTo []string `json:"conformsTo"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode conformance: %v", err)
+	}
+	if len(response.ConformsTo) != len(ogcConformanceClasses) {
+		t.Errorf("unexpected conformance classes: %v", response.ConformsTo)
+	}
+}
+
+func TestOgcItemsHandlerInvalidRequest(t *testing.T) {
+	t.Logf("Test: OgcItemsHandler - unknown collection and invalid query params")
+
+	cases := []struct {
+		collectionId string
+		query        string
+		status       int
+	}{
+		{"lv6", "", http.StatusNotFound},
+		{"countries", "", http.StatusNotFound},
+		{"lv1", "limit=ten", http.StatusBadRequest},
+		{"lv1", "bbox=1,2,3", http.StatusBadRequest},
+		{"lv1", "start-after-id=not-uuid", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest("GET", "/ogc/collections/"+c.collectionId+"/items?"+c.query, nil)
+		request.SetPathValue("collectionId", c.collectionId)
+		recorder := httptest.NewRecorder()
+		(&Handler{}).OgcItemsHandler(recorder, request, "", nil)
+		if recorder.Code != c.status {
+			t.Errorf("unexpected status for %s?%s: %d. Expected %d", c.collectionId, c.query, recorder.Code, c.status)
+		}
+	}
+}
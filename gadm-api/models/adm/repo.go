@@ -23,6 +23,92 @@ type Adm struct {
 
 	Geom json.RawMessage `db:"geom" json:"geom,omitempty"`
 	Bbox []float64       `db:"bbox" json:"bbox,omitempty"`
+
+	OverlapAreaSqM         *float64 `db:"overlap_area_sq_m" json:"overlap_area_sq_m,omitempty"`
+	OverlapFractionOfInput *float64 `db:"overlap_fraction_of_input" json:"overlap_fraction_of_input,omitempty"`
+	OverlapFractionOfAdm   *float64 `db:"overlap_fraction_of_adm" json:"overlap_fraction_of_adm,omitempty"`
+
+	// reverse geocoding only, see pointMatchOpts
+	MatchType       string              `db:"-" json:"match_type,omitempty"`
+	DistanceM       *float64            `db:"distance_m" json:"distance_m,omitempty"`
+	BorderProximity *admBorderProximity `db:"-" json:"border_proximity,omitempty"`
+
+	// value of sort expression when not sorting by id, see admSort
+	SortValue any `db:"sort_value" json:"-"`
+
+	// tabular export only
+	AreaSqM     *float64 `db:"area_sq_m" json:"-"`
+	Wkt         *string  `db:"wkt" json:"-"`
+	Wkb         *string  `db:"wkb" json:"-"`
+	CentroidLat *float64 `db:"centroid_lat" json:"-"`
+	CentroidLng *float64 `db:"centroid_lng" json:"-"`
+}
+
+type AdmHierarchyEntry struct {
+	ID    string          `db:"id" json:"id"`
+	Level int             `db:"lv" json:"lv"`
+	Gid   *string         `db:"gid" json:"gid"`
+	Name  *string         `db:"name" json:"name"`
+	Geom  json.RawMessage `db:"geom" json:"geom,omitempty"`
+}
+
+type indexedAdm struct {
+	Idx int `db:"idx"`
+	Adm
+}
+
+type lookupAdm struct {
+	LookupKey string `db:"lookup_key"`
+	Adm
+}
+
+type admSearchResult struct {
+	Adm
+	Gid         *string `db:"gid" json:"gid"`
+	Name        *string `db:"name" json:"name"`
+	MatchedName string  `db:"matched_name" json:"matched_name"`
+	Score       float64 `db:"score" json:"score"`
+	Breadcrumb  string  `json:"breadcrumb"`
+
+	BreadcrumbNames json.RawMessage `db:"breadcrumb_names" json:"-"`
+}
+
+type admMetadataKey struct {
+	Name  string   `db:"name"`
+	Types []string `db:"types"`
+}
+
+const (
+	metadataValueTypeString  = "string"
+	metadataValueTypeBoolean = "boolean"
+	metadataValueTypeInteger = "integer"
+	metadataValueTypeNumber  = "number"
+	metadataValueTypeJson    = "json"
+)
+
+// Single type of values stored under the key, nulls aside. Keys with values
+// of different types, objects or arrays are typed as JSON.
+func (key admMetadataKey) valueType() string {
+	types := map[string]bool{}
+	for _, t := range key.Types {
+		if t != "null" {
+			types[t] = true
+		}
+	}
+
+	switch {
+	case len(types) == 1 && types[metadataValueTypeString]:
+		return metadataValueTypeString
+	case len(types) == 1 && types[metadataValueTypeBoolean]:
+		return metadataValueTypeBoolean
+	case len(types) == 1 && types[metadataValueTypeInteger]:
+		return metadataValueTypeInteger
+	case len(types) == 1 && types[metadataValueTypeNumber],
+		len(types) == 2 && types[metadataValueTypeNumber] && types[metadataValueTypeInteger]:
+		return metadataValueTypeNumber
+	default:
+		return metadataValueTypeJson
+	}
 }
 
 type Repo struct {
@@ -33,8 +119,8 @@ func NewAdmRepo(pg *pgxpool.Pool) *Repo {
 	return &Repo{pgConn: pg}
 }
 
-func (repo *Repo) GetAdmNeighbors(ctx context.Context, admId string) ([]Adm, error) {
-	sql, args, err := getAdmNeighborsSqlQuery(admId)
+func (repo *Repo) GetAdmNeighbors(ctx context.Context, admId string, projection admProjection) ([]Adm, error) {
+	sql, args, err := getAdmNeighborsSqlQuery(admId, projection)
 	if err != nil {
 		return nil, fmt.Errorf("failed_to_build_query: %w", err)
 	}
@@ -55,15 +141,19 @@ func (repo *Repo) GetAdmNeighbors(ctx context.Context, admId string) ([]Adm, err
 	return result, nil
 }
 
-func (repo *Repo) GetAdmForPoint(ctx context.Context, point utils.Point) (Adm, error) {
-	sql, args, err := getAdmForPointSqlQuery(point)
+func (repo *Repo) GetAdmForPoint(ctx context.Context, point utils.Point, projection admProjection) (Adm, error) {
+	sql, args, err := getAdmForPointSqlQuery(point, projection)
 	if err != nil {
 		return Adm{}, fmt.Errorf("failed_to_build_query: %w", err)
 	}
 
-	var adm Adm
-	err = repo.pgConn.QueryRow(ctx, sql, args...).
-		Scan(&adm.Metadata, &adm.ID, &adm.Level, &adm.GeomHash)
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return Adm{}, fmt.Errorf(
+			"failed_to_query_database_for_adm_for_lat_lng: sql_query: %s: %w",
+			sql, err)
+	}
+	adm, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[Adm])
 	if err != nil {
 		return Adm{}, fmt.Errorf(
 			"failed_to_query_database_for_adm_for_lat_lng: sql_query: %s: %w",
@@ -72,21 +162,249 @@ func (repo *Repo) GetAdmForPoint(ctx context.Context, point utils.Point) (Adm, e
 	return adm, nil
 }
 
-func (repo *Repo) GetAdmById(ctx context.Context, admId string) (Adm, error) {
-	sql, args, err := getSelectOneAdmByIdSqlQuery(admId)
+func (repo *Repo) GetNearestAdmForPoint(
+	ctx context.Context,
+	point utils.Point,
+	maxDistanceM float64,
+	projection admProjection,
+) (Adm, error) {
+	sql, args, err := getNearestAdmForPointSqlQuery(point, maxDistanceM, projection)
 	if err != nil {
 		return Adm{}, fmt.Errorf("failed_to_build_query: %w", err)
 	}
 
-	var adm Adm
-	err = repo.pgConn.QueryRow(ctx, sql, args...).
-		Scan(&adm.Metadata, &adm.ID, &adm.Level, &adm.GeomHash)
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return Adm{}, fmt.Errorf(
+			"failed_to_query_database_for_nearest_adm_for_lat_lng: sql_query: %s: %w",
+			sql, err)
+	}
+	adm, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[Adm])
+	if err != nil {
+		return Adm{}, fmt.Errorf(
+			"failed_to_query_database_for_nearest_adm_for_lat_lng: sql_query: %s: %w",
+			sql, err)
+	}
+	return adm, nil
+}
+
+func (repo *Repo) GetAdmBoundaryDistance(ctx context.Context, admId string, point utils.Point) (float64, error) {
+	sql, args, err := getAdmBoundaryDistanceSqlQuery(admId, point)
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	var distanceM float64
+	if err := repo.pgConn.QueryRow(ctx, sql, args...).Scan(&distanceM); err != nil {
+		return 0, fmt.Errorf(
+			"failed_to_query_database_for_adm_boundary_distance: sql_query: %s: %w",
+			sql, err)
+	}
+	return distanceM, nil
+}
+
+func (repo *Repo) GetAdmsNearPoint(
+	ctx context.Context,
+	point utils.Point,
+	lv int,
+	excludeAdmId string,
+	toleranceM float64,
+	projection admProjection,
+) ([]Adm, error) {
+	sql, args, err := getAdmsNearPointSqlQuery(point, lv, excludeAdmId, toleranceM, projection)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed_to_query_database_for_adms_near_point: sql_query: %s: %w",
+			sql, err)
+	}
+	adms, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[Adm])
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed_to_query_database_for_adms_near_point: sql_query: %s: %w",
+			sql, err)
+	}
+	return adms, nil
+}
+
+func (repo *Repo) GetAdmsForPoints(
+	ctx context.Context,
+	idxs []int,
+	points []utils.Point,
+	projection admProjection,
+) ([]indexedAdm, error) {
+	sql, args, err := getAdmsForPointsSqlQuery(idxs, points, projection)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adms_for_points: sql_query: %s: %w", sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[indexedAdm])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (repo *Repo) GetAdmAncestry(ctx context.Context, admId string, includeGeometry bool) ([]AdmHierarchyEntry, error) {
+	sql, args, err := getAdmAncestrySqlQuery(admId, includeGeometry)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adm_ancestry: sql_query: %s: %w", sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[AdmHierarchyEntry])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (repo *Repo) GetAdmById(ctx context.Context, admId string, projection admProjection) (Adm, error) {
+	sql, args, err := getSelectOneAdmByIdSqlQuery(admId, projection)
+	if err != nil {
+		return Adm{}, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return Adm{}, fmt.Errorf("failed_to_query_database_for_adm_by_id: sql_query: %s: %w", sql, err)
+	}
+	adm, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[Adm])
 	if err != nil {
 		return Adm{}, fmt.Errorf("failed_to_query_database_for_adm_by_id: sql_query: %s: %w", sql, err)
 	}
 	return adm, nil
 }
 
+func (repo *Repo) GetAdmStats(ctx context.Context, admId string) (admStats, error) {
+	sql, args, err := getAdmStatsSqlQuery(admId)
+	if err != nil {
+		return admStats{}, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return admStats{}, fmt.Errorf("failed_to_query_database_for_adm_stats: sql_query: %s: %w", sql, err)
+	}
+	stats, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[admStats])
+	if err != nil {
+		return admStats{}, fmt.Errorf("failed_to_query_database_for_adm_stats: sql_query: %s: %w", sql, err)
+	}
+	return stats, nil
+}
+
+func (repo *Repo) GetAdmBorder(ctx context.Context, admId string, neighborId string) (admBorder, error) {
+	sql, args, err := getAdmBorderSqlQuery(admId, neighborId)
+	if err != nil {
+		return admBorder{}, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return admBorder{}, fmt.Errorf("failed_to_query_database_for_adm_border: sql_query: %s: %w", sql, err)
+	}
+	border, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[admBorder])
+	if err != nil {
+		return admBorder{}, fmt.Errorf("failed_to_query_database_for_adm_border: sql_query: %s: %w", sql, err)
+	}
+	return border, nil
+}
+
+func (repo *Repo) GetAdmsByIdentifiers(
+	ctx context.Context,
+	idType admIdType,
+	ids []string,
+	projection admProjection,
+) ([]lookupAdm, error) {
+	sql, args, err := getSelectAdmsByIdentifiersSqlQuery(idType, ids, projection)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adms_by_identifiers: sql_query: %s: %w", sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[lookupAdm])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (repo *Repo) GetAdmRelatives(
+	ctx context.Context,
+	admId string,
+	relation admRelation,
+	depth int,
+	options admQueryOpts,
+) ([]Adm, error) {
+	sql, args, err := getSelectAdmRelativesSqlQuery(admId, relation, depth, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adm_%s: sql_query: %s: %w", relation, sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[Adm])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (repo *Repo) SearchAdms(
+	ctx context.Context,
+	searchQuery string,
+	lv *int,
+	country string,
+	limit int,
+	projection admProjection,
+) ([]admSearchResult, error) {
+	sql, args, err := getSearchAdmsSqlQuery(searchQuery, lv, country, limit, projection)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adm_search: sql_query: %s: %w", sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[admSearchResult])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+
+	return result, nil
+}
+
 func (repo *Repo) GetAdmsDirectChildrenForId(ctx context.Context, admId string, lv int) ([]Adm, error) {
 	sql, args, err := getSelectAdmDirectChildrenForIdSqlQuery(admId, lv)
 	if err != nil {
@@ -140,6 +458,59 @@ func (repo *Repo) GetAdms(ctx context.Context, options admQueryOpts) ([]Adm, err
 	return result, nil
 }
 
+func (repo *Repo) GetAdmsForGeometry(
+	ctx context.Context,
+	geometry string,
+	predicate spatialPredicate,
+	options admQueryOpts,
+) ([]Adm, error) {
+	sql, args, err := getSelectAdmsForGeometrySqlQuery(geometry, predicate, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adms_for_geometry: sql_query: %s: %w", sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[Adm])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (repo *Repo) GetAdmTile(ctx context.Context, tile tileCoords, levels []int) ([]byte, error) {
+	sql, args, err := getAdmTileSqlQuery(tile, levels)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	var mvt []byte
+	err = repo.pgConn.QueryRow(ctx, sql, args...).Scan(&mvt)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adm_tile: sql_query: %s: %w", sql, err)
+	}
+	return mvt, nil
+}
+
+func (repo *Repo) IsGeographicSrid(ctx context.Context, srid int) (bool, error) {
+	sql, args, err := getSpatialRefSysSqlQuery(srid)
+	if err != nil {
+		return false, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	var isGeographic bool
+	err = repo.pgConn.QueryRow(ctx, sql, args...).Scan(&isGeographic)
+	if err != nil {
+		return false, fmt.Errorf("failed_to_query_database_for_spatial_ref_sys: sql_query: %s: %w", sql, err)
+	}
+	return isGeographic, nil
+}
+
 func (repo *Repo) GetNeighbors(ctx context.Context, admId string) ([]Adm, error) {
 	sql, args, err := getNeighborsSqlQuery(admId)
 	if err != nil {
@@ -192,6 +563,45 @@ func (repo *Repo) UpsertAdmNeighborsBatch(ctx context.Context, admId string, nei
 	return nil
 }
 
+type admNeighborEdge struct {
+	ID         string `db:"id"`
+	NeighborID string `db:"neighbor_id"`
+}
+
+func (repo *Repo) GetAdmNeighborEdges(ctx context.Context, ids []string, lv int) ([]admNeighborEdge, error) {
+	sql, args, err := getAdmNeighborEdgesSqlQuery(ids, lv)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adm_neighbor_edges: sql_query: %s: %w", sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[admNeighborEdge])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+	return result, nil
+}
+
+// Rebuilds gadm.adm_names from adm metadata, returns number of names.
+func (repo *Repo) RefreshAdmNames(ctx context.Context) (int64, error) {
+	sql, args, err := getRefreshAdmNamesSqlQuery()
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	var namesCount int64
+	err = repo.pgConn.QueryRow(ctx, sql, args...).Scan(&namesCount)
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_refresh_adm_names: sql_query: %s: %w", sql, err)
+	}
+	return namesCount, nil
+}
+
 func (repo *Repo) GetLeafAdms(ctx context.Context, startAfterId string, batchSize int) ([]Adm, error) {
 	sql, args, err := getSelectLeafAdmsSqlQuery(startAfterId, batchSize)
 	if err != nil {
@@ -239,40 +649,118 @@ func (repo *Repo) IterateLeafAdms(
 	}
 }
 
+func (repo *Repo) CountAdms(ctx context.Context, options admQueryOpts) (int64, error) {
+	sql, args, err := getCountAdmsSqlQuery(options)
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	var count int64
+	if err := repo.pgConn.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed_to_count_adms: sql_query: %s: %w", sql, err)
+	}
+	return count, nil
+}
+
+func (repo *Repo) EstimateAdmsCount(ctx context.Context, options admQueryOpts) (int64, error) {
+	sql, args, err := getEstimateAdmsCountSqlQuery(options)
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	var plan []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := repo.pgConn.QueryRow(ctx, sql, args...).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("failed_to_estimate_adms_count: sql_query: %s: %w", sql, err)
+	}
+	if len(plan) == 0 {
+		return 0, fmt.Errorf("failed_to_estimate_adms_count: empty_plan")
+	}
+	return int64(plan[0].Plan.PlanRows), nil
+}
+
+func (repo *Repo) GetAdmMetadataKeys(ctx context.Context, options admQueryOpts) ([]admMetadataKey, error) {
+	sql, args, err := getAdmMetadataKeysSqlQuery(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query: %w", err)
+	}
+
+	rows, err := repo.pgConn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_database_for_adm_metadata_keys: sql_query: %s: %w", sql, err)
+	}
+	defer rows.Close()
+
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[admMetadataKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_collect_rows: %w", err)
+	}
+
+	return result, nil
+}
+
+// Streams adms through a server-side cursor, so memory use doesn't grow with
+// the number of adms and statement timeout applies to each fetch instead of
+// the whole stream. Next batch is fetched only once ch takes the previous
+// one, which keeps the query from running ahead of slow consumers.
 func (repo *Repo) GetGeojsonl(ctx context.Context, opts admQueryOpts, ch chan<- Adm) error {
 	defer close(ch)
 
-	sql, args, err := getSelectAdmsSqlQuery(opts)
+	sql, args, err := getDeclareAdmsCursorSqlQuery(opts)
 	if err != nil {
 		return fmt.Errorf("failed_to_build_query: %w", err)
 	}
 	logger.Debug("sql: %s", sql)
-	rows, err := repo.pgConn.Query(ctx, sql, args...)
 
+	tx, err := repo.pgConn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return fmt.Errorf("failed_to_query_database: %w", err)
+		return fmt.Errorf("failed_to_begin_transaction: %w", err)
+	}
+	// nothing to commit, rollback also closes the cursor
+	defer tx.Rollback(context.Background())
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed_to_declare_cursor: %w", err)
+	}
+
+	for {
+		count, err := fetchAdmsFromCursor(ctx, tx, ch)
+		if err != nil {
+			return err
+		}
+		if count < ADMS_CURSOR_FETCH_SIZE {
+			return nil
+		}
+	}
+}
+
+func fetchAdmsFromCursor(ctx context.Context, tx pgx.Tx, ch chan<- Adm) (int, error) {
+	rows, err := tx.Query(ctx, getFetchAdmsCursorSqlQuery())
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_fetch_from_cursor: %w", err)
 	}
 	defer rows.Close()
 
+	count := 0
 	for rows.Next() {
-		result, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[Adm])
+		adm, err := pgx.RowToStructByNameLax[Adm](rows)
 		if err != nil {
-			return fmt.Errorf("failed_to_scan_adm %w", err)
-		}
-		for _, adm := range result {
-			ch <- adm
+			return count, fmt.Errorf("failed_to_scan_adm %w", err)
 		}
+		count++
 
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			continue
+			return count, ctx.Err()
+		case ch <- adm:
 		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("failed_to_iterate_rows: %w", err)
+		return count, fmt.Errorf("failed_to_iterate_rows: %w", err)
 	}
-	return nil
+	return count, nil
 }
@@ -3,11 +3,16 @@ package adm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gadm-api/logger"
 	"gadm-api/utils"
+	"io"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	geojson "github.com/paulmach/go.geojson"
 	"golang.org/x/sync/errgroup"
 )
@@ -20,22 +25,284 @@ func NewAdmService(repo *Repo) *Service {
 	return &Service{repo: repo}
 }
 
-func (service *Service) GetAdmNeighbors(ctx context.Context, admId string) ([]Adm, error) {
-	result, err := service.repo.GetAdmNeighbors(ctx, admId)
+func (service *Service) GetAdmNeighbors(ctx context.Context, admId string, projection admProjection) ([]Adm, error) {
+	result, err := service.repo.GetAdmNeighbors(ctx, admId, projection)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (service *Service) GetAdmForPoint(ctx context.Context, point utils.Point) (Adm, error) {
-	result, err := service.repo.GetAdmForPoint(ctx, point)
+// Returns adm containing the point, with nearest fallback the closest one
+// within max distance is returned when no adm contains it.
+func (service *Service) GetAdmForPoint(
+	ctx context.Context,
+	point utils.Point,
+	projection admProjection,
+	matchOpts pointMatchOpts,
+) (Adm, error) {
+	result, err := service.repo.GetAdmForPoint(ctx, point, projection)
+	if err == nil {
+		distanceM := 0.0
+		result.MatchType = admMatchContains
+		result.DistanceM = &distanceM
+	} else {
+		if !errors.Is(err, pgx.ErrNoRows) || !matchOpts.nearest {
+			return Adm{}, err
+		}
+		result, err = service.repo.GetNearestAdmForPoint(ctx, point, matchOpts.maxDistanceM, projection)
+		if err != nil {
+			return Adm{}, err
+		}
+		result.MatchType = admMatchNearest
+	}
+
+	if matchOpts.toleranceM == nil {
+		return result, nil
+	}
+	result.BorderProximity, err = service.getAdmBorderProximity(ctx, point, result, *matchOpts.toleranceM, projection)
+	if err != nil {
+		return Adm{}, err
+	}
+	return result, nil
+}
+
+func (service *Service) getAdmBorderProximity(
+	ctx context.Context,
+	point utils.Point,
+	adm Adm,
+	toleranceM float64,
+	projection admProjection,
+) (*admBorderProximity, error) {
+	// point outside of nearest adm is as far from its boundary as from it
+	boundaryDistanceM := *adm.DistanceM
+	if adm.MatchType == admMatchContains {
+		var err error
+		boundaryDistanceM, err = service.repo.GetAdmBoundaryDistance(ctx, adm.ID, point)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nearbyProjection := admProjection{fields: projection.fields, geometry: admGeometryNone}
+	nearby, err := service.repo.GetAdmsNearPoint(ctx, point, adm.Level, adm.ID, toleranceM, nearbyProjection)
+	if err != nil {
+		return nil, err
+	}
+	return newAdmBorderProximity(toleranceM, boundaryDistanceM, nearby), nil
+}
+
+type admWithHierarchy struct {
+	Adm
+	Hierarchy []AdmHierarchyEntry `json:"hierarchy"`
+}
+
+func (service *Service) GetAdmWithHierarchyForPoint(
+	ctx context.Context,
+	point utils.Point,
+	projection admProjection,
+	matchOpts pointMatchOpts,
+	includeGeometry bool,
+) (admWithHierarchy, error) {
+	adm, err := service.GetAdmForPoint(ctx, point, projection, matchOpts)
+	if err != nil {
+		return admWithHierarchy{}, err
+	}
+
+	hierarchy, err := service.repo.GetAdmAncestry(ctx, adm.ID, includeGeometry)
+	if err != nil {
+		return admWithHierarchy{}, err
+	}
+
+	return admWithHierarchy{Adm: adm, Hierarchy: hierarchy}, nil
+}
+
+type batchPoint struct {
+	id    interface{}
+	point *utils.Point
+}
+
+const (
+	resultStatusFound           = "found"
+	resultStatusNotFound        = "not_found"
+	resultStatusInvalidGeometry = "invalid_geometry"
+)
+
+type batchReverseGeocodeResult struct {
+	Index  int         `json:"index"`
+	Id     interface{} `json:"id,omitempty"`
+	Status string      `json:"status"`
+	Adm    *Adm        `json:"adm,omitempty"`
+}
+
+func (service *Service) GetAdmsForPoints(
+	ctx context.Context,
+	batchPoints []batchPoint,
+	projection admProjection,
+) ([]batchReverseGeocodeResult, error) {
+	results := make([]batchReverseGeocodeResult, len(batchPoints))
+	idxs := make([]int, 0, len(batchPoints))
+	points := make([]utils.Point, 0, len(batchPoints))
+	for i, batchPoint := range batchPoints {
+		results[i] = batchReverseGeocodeResult{
+			Index:  i,
+			Id:     batchPoint.id,
+			Status: resultStatusNotFound,
+		}
+		if batchPoint.point == nil {
+			results[i].Status = resultStatusInvalidGeometry
+			continue
+		}
+		idxs = append(idxs, i)
+		points = append(points, *batchPoint.point)
+	}
+
+	if len(points) == 0 {
+		return results, nil
+	}
+
+	adms, err := service.repo.GetAdmsForPoints(ctx, idxs, points, projection)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, adm := range adms {
+		results[adm.Idx].Status = resultStatusFound
+		results[adm.Idx].Adm = &adm.Adm
+	}
+	return results, nil
+}
+
+func (service *Service) GetAdmById(ctx context.Context, admId string, projection admProjection) (Adm, error) {
+	result, err := service.repo.GetAdmById(ctx, admId, projection)
 	if err != nil {
 		return Adm{}, err
 	}
 	return result, nil
 }
 
+type admLookupResult struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	Adms   []Adm  `json:"adms,omitempty"`
+}
+
+func (service *Service) LookupAdms(
+	ctx context.Context,
+	idType admIdType,
+	ids []string,
+	projection admProjection,
+) ([]admLookupResult, error) {
+	results := make([]admLookupResult, len(ids))
+	normalizedIds := make([]string, 0, len(ids))
+	for i, id := range ids {
+		results[i] = admLookupResult{Id: id, Status: resultStatusNotFound}
+		if normalizedId, ok := idType.normalize(id); ok {
+			normalizedIds = append(normalizedIds, normalizedId)
+		}
+	}
+
+	if len(normalizedIds) == 0 {
+		return results, nil
+	}
+
+	adms, err := service.repo.GetAdmsByIdentifiers(ctx, idType, normalizedIds, projection)
+	if err != nil {
+		return nil, err
+	}
+
+	admsByKey := make(map[string][]Adm, len(adms))
+	for _, adm := range adms {
+		admsByKey[adm.LookupKey] = append(admsByKey[adm.LookupKey], adm.Adm)
+	}
+
+	for i, id := range ids {
+		normalizedId, ok := idType.normalize(id)
+		if !ok {
+			continue
+		}
+		if matches, found := admsByKey[normalizedId]; found {
+			results[i].Status = resultStatusFound
+			results[i].Adms = matches
+		}
+	}
+	return results, nil
+}
+
+func (service *Service) SearchAdms(
+	ctx context.Context,
+	searchQuery string,
+	lv *int,
+	country string,
+	limit int,
+	projection admProjection,
+) ([]admSearchResult, error) {
+	results, err := service.repo.SearchAdms(ctx, searchQuery, lv, country, limit, projection)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		breadcrumb, err := getAdmBreadcrumb(results[i].BreadcrumbNames, results[i].Level)
+		if err != nil {
+			logger.Warning("failed_to_build_breadcrumb: adm_id=%s: %v", results[i].ID, err)
+			continue
+		}
+		results[i].Breadcrumb = breadcrumb
+	}
+	return results, nil
+}
+
+// GADM metadata of every adm carries names of all its ancestors
+// (country, name_1 ... name_{lv-1}) so no tree traversal is needed.
+func getAdmBreadcrumb(breadcrumbNames json.RawMessage, level int) (string, error) {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(breadcrumbNames, &metadata); err != nil {
+		return "", fmt.Errorf("failed_to_unmarshal_breadcrumb_names: %w", err)
+	}
+
+	names := make([]string, 0, level)
+	for lv := 0; lv < level; lv++ {
+		key := fmt.Sprintf("name_%d", lv)
+		if lv == 0 {
+			key = "country"
+		}
+		if name, ok := metadata[key].(string); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " > "), nil
+}
+
+// Checks that PostGIS knows the crs and sets its axis order.
+func (service *Service) ResolveCrs(ctx context.Context, c crs) (crs, error) {
+	if c.isDefault() {
+		return c, nil
+	}
+
+	isGeographic, err := service.repo.IsGeographicSrid(ctx, c.srid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return crs{}, fmt.Errorf("%w: srid=%d", errUnsupportedCrs, c.srid)
+		}
+		return crs{}, err
+	}
+	c.flipAxes = isGeographic
+	return c, nil
+}
+
+func (service *Service) GetAdmTile(
+	ctx context.Context,
+	tile tileCoords,
+	zoomRanges TileLevelZoomRanges,
+) ([]byte, error) {
+	levels := zoomRanges.levelsForZoom(tile.z)
+	if len(levels) == 0 {
+		return nil, nil
+	}
+	return service.repo.GetAdmTile(ctx, tile, levels)
+}
+
 func (service *Service) GetAdmsFc(ctx context.Context, options admQueryOpts) (*geojson.FeatureCollection, error) {
 	adms, err := service.repo.GetAdms(ctx, options)
 	if err != nil {
@@ -44,57 +311,452 @@ func (service *Service) GetAdmsFc(ctx context.Context, options admQueryOpts) (*g
 	return convertAdmsToFeatureCollection(adms)
 }
 
-func (service *Service) getAdmGeojsonlStream(
+func (service *Service) GetAdmStats(ctx context.Context, admId string) (admStats, error) {
+	stats, err := service.repo.GetAdmStats(ctx, admId)
+	if err != nil {
+		return admStats{}, err
+	}
+	stats.Compactness = getAdmCompactness(stats.AreaSqM, stats.PerimeterM, stats.ConvexHullAreaSqM)
+	return stats, nil
+}
+
+func (service *Service) GetAdmBorder(ctx context.Context, admId string, neighborId string) (admBorder, error) {
+	border, err := service.repo.GetAdmBorder(ctx, admId, neighborId)
+	if err != nil {
+		return admBorder{}, err
+	}
+	return border, nil
+}
+
+// Traversal stays within one level, neighbors of other levels are skipped.
+func (service *Service) getAdmNeighborsOfLevel(lv int) admNeighborsFunc {
+	return func(ctx context.Context, ids []string) (map[string][]string, error) {
+		edges, err := service.repo.GetAdmNeighborEdges(ctx, ids, lv)
+		if err != nil {
+			return nil, err
+		}
+		adjacency := map[string][]string{}
+		for _, edge := range edges {
+			adjacency[edge.ID] = append(adjacency[edge.ID], edge.NeighborID)
+		}
+		return adjacency, nil
+	}
+}
+
+// Adms by id in order of given ids.
+func (service *Service) getAdmsInOrder(ctx context.Context, ids []string, projection admProjection) ([]Adm, error) {
+	if len(ids) == 0 {
+		return []Adm{}, nil
+	}
+	lookupAdms, err := service.repo.GetAdmsByIdentifiers(ctx, admIdTypeId, ids, projection)
+	if err != nil {
+		return nil, err
+	}
+	admsById := map[string]Adm{}
+	for _, adm := range lookupAdms {
+		admsById[adm.LookupKey] = adm.Adm
+	}
+
+	adms := make([]Adm, 0, len(ids))
+	for _, id := range ids {
+		adm, ok := admsById[id]
+		if !ok {
+			// adm deleted while traversing
+			return nil, fmt.Errorf("failed_to_get_adm: adm_id=%s: %w", id, pgx.ErrNoRows)
+		}
+		adms = append(adms, adm)
+	}
+	return adms, nil
+}
+
+type admNeighborhoodEntry struct {
+	Adm
+	Hops int `json:"hops"`
+}
+
+type admNeighborhood struct {
+	ID      string                 `json:"id"`
+	Level   int                    `json:"lv"`
+	MaxHops int                    `json:"max_hops"`
+	Adms    []admNeighborhoodEntry `json:"adms"`
+}
+
+func (service *Service) GetAdmNeighborhood(
+	ctx context.Context,
+	admId string,
+	maxHops int,
+	projection admProjection,
+) (admNeighborhood, error) {
+	start, err := service.repo.GetAdmById(ctx, admId, admProjection{fields: []string{}})
+	if err != nil {
+		return admNeighborhood{}, err
+	}
+
+	hops, err := getAdmNeighborhoodHops(ctx, service.getAdmNeighborsOfLevel(start.Level), start.ID, maxHops)
+	if err != nil {
+		return admNeighborhood{}, err
+	}
+	ids := make([]string, 0, len(hops))
+	for id := range hops {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		if hops[a] != hops[b] {
+			return hops[a] - hops[b]
+		}
+		return strings.Compare(a, b)
+	})
+
+	adms, err := service.getAdmsInOrder(ctx, ids, projection)
+	if err != nil {
+		return admNeighborhood{}, err
+	}
+	entries := make([]admNeighborhoodEntry, len(adms))
+	for i, adm := range adms {
+		entries[i] = admNeighborhoodEntry{Adm: adm, Hops: hops[adm.ID]}
+	}
+	return admNeighborhood{ID: start.ID, Level: start.Level, MaxHops: maxHops, Adms: entries}, nil
+}
+
+type admPath struct {
+	Hops int `json:"hops"`
+	// from start to target, both included
+	Adms []Adm `json:"adms"`
+}
+
+func (service *Service) GetAdmPath(
+	ctx context.Context,
+	admId string,
+	targetId string,
+	maxHops int,
+	projection admProjection,
+) (admPath, error) {
+	ends := [2]Adm{}
+	for i, id := range []string{admId, targetId} {
+		adm, err := service.repo.GetAdmById(ctx, id, admProjection{fields: []string{}})
+		if err != nil {
+			return admPath{}, err
+		}
+		ends[i] = adm
+	}
+	start, target := ends[0], ends[1]
+	if start.Level != target.Level {
+		return admPath{}, fmt.Errorf("%w: lv=%d target_lv=%d", errAdmLevelMismatch, start.Level, target.Level)
+	}
+
+	ids, err := getAdmShortestPath(ctx, service.getAdmNeighborsOfLevel(start.Level), start.ID, target.ID, maxHops)
+	if err != nil {
+		return admPath{}, err
+	}
+	adms, err := service.getAdmsInOrder(ctx, ids, projection)
+	if err != nil {
+		return admPath{}, err
+	}
+	return admPath{Hops: len(adms) - 1, Adms: adms}, nil
+}
+
+type admPage struct {
+	adms    []Adm
+	hasNext bool
+	// boundaries of neighboring pages, nil when there is none
+	next *admKeyset
+	prev *admKeyset
+	// adms matching filters on all pages
+	numberMatched           int64
+	numberMatchedIsEstimate bool
+}
+
+// Adms of one page, numberMatched is only counted with countMatched.
+func (service *Service) GetAdmsPage(ctx context.Context, opts admQueryOpts, countMatched bool) (admPage, error) {
+	page := admPage{}
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		adms, err := service.repo.GetAdms(gctx, opts)
+		page.adms = adms
+		return err
+	})
+	g.Go(func() error {
+		if !countMatched {
+			return nil
+		}
+		countOpts := opts
+		countOpts.startAfterId = nil
+		countOpts.startAfterFid = nil
+		countOpts.keyset = nil
+		countOpts.sort = admSort{}
+		countOpts.batchSize = nil
+		count, exact, err := service.estimateAdmsCount(gctx, countOpts)
+		page.numberMatched = count
+		page.numberMatchedIsEstimate = !exact
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return admPage{}, err
+	}
+
+	// backward pages are queried in reverse order
+	backward := opts.keyset != nil && opts.keyset.backward
+	if backward {
+		slices.Reverse(page.adms)
+	}
+	if len(page.adms) == 0 {
+		return page, nil
+	}
+
+	isFull := opts.batchSize != nil && len(page.adms) == *opts.batchSize
+	page.hasNext = isFull || backward
+	if page.hasNext {
+		page.next = newAdmKeyset(page.adms[len(page.adms)-1], false)
+	}
+	hasPrev := (backward && isFull) || (!backward && (opts.keyset != nil || opts.startAfterId != nil))
+	if hasPrev {
+		page.prev = newAdmKeyset(page.adms[0], true)
+	}
+	return page, nil
+}
+
+func (service *Service) GetAdmsForGeometryFc(
+	ctx context.Context,
+	geometry *geojson.Geometry,
+	predicate spatialPredicate,
+	options admQueryOpts,
+) (*geojson.FeatureCollection, error) {
+	geometryJson, err := json.Marshal(geometry)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_marshal_input_geometry: %w", err)
+	}
+
+	adms, err := service.repo.GetAdmsForGeometry(ctx, string(geometryJson), predicate, options)
+	if err != nil {
+		return nil, err
+	}
+	return convertAdmsToFeatureCollection(adms)
+}
+
+func (service *Service) GetAdmRelativesFc(
+	ctx context.Context,
+	admId string,
+	relation admRelation,
+	depth *int,
+	options admQueryOpts,
+) (*geojson.FeatureCollection, error) {
+	adms, err := service.repo.GetAdmRelatives(ctx, admId, relation, relation.maxDepth(depth), options)
+	if err != nil {
+		return nil, err
+	}
+	return convertAdmsToFeatureCollection(adms)
+}
+
+var errInvalidTopologyLevel = errors.New("invalid_topology_level")
+
+// Topology of descendants of the adm at level lv, direct children when lv
+// is not given.
+func (service *Service) GetAdmDescendantsTopology(
 	ctx context.Context,
-	ch chan<- json.RawMessage,
+	admId string,
+	lv *int,
+	fields []string,
+	quantization int,
+) (*topology, error) {
+	parent, err := service.repo.GetAdmById(ctx, admId, admProjection{})
+	if err != nil {
+		return nil, err
+	}
+
+	descendantsLv := parent.Level + 1
+	if lv != nil {
+		descendantsLv = *lv
+	}
+	if descendantsLv <= parent.Level {
+		return nil, fmt.Errorf("%w: lv=%d parent_lv=%d", errInvalidTopologyLevel, descendantsLv, parent.Level)
+	}
+
+	options, err := NewAdmQueryOptsBuilder().
+		SetLvAndBatchSize(&descendantsLv, nil).
+		SetProjection(admProjection{fields: fields, geometry: admGeometryFull}).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_build_query_options: %w", err)
+	}
+
+	adms, err := service.repo.GetAdmRelatives(ctx, admId, admRelationDescendants, descendantsLv-parent.Level, options)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := convertAdmsToFeatureCollection(adms)
+	if err != nil {
+		return nil, err
+	}
+	return convertFeaturesToTopology(fc.Features, quantization)
+}
+
+func (service *Service) GetAdms(ctx context.Context, options admQueryOpts) ([]Adm, error) {
+	return service.repo.GetAdms(ctx, options)
+}
+
+func (service *Service) GetDefaultCsvColumns(ctx context.Context, opts admQueryOpts) ([]string, error) {
+	metadataKeys, err := service.repo.GetAdmMetadataKeys(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return getDefaultCsvColumns(metadataKeys), nil
+}
+
+func (service *Service) getAdmCsvStream(
+	ctx context.Context,
+	ch chan<- []byte,
 	opts admQueryOpts,
+	encoder *admCsvEncoder,
 ) error {
 	defer close(ch)
 
-	admCh := make(chan Adm, 10)
-	go func() {
-		err := service.repo.GetGeojsonl(ctx, opts, admCh)
-		if err != nil {
-			logger.Error("failed_to_get_geojsonl %v", err)
-			return
+	return service.writeAdms(ctx, opts, nil, func(ctx context.Context, admCh <-chan Adm) error {
+		for adm := range admCh {
+			data, err := encoder.encodeAdm(adm)
+			if err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ch <- data:
+			}
 		}
-	}()
+		return nil
+	})
+}
 
-	for adm := range admCh {
-		admGeojson, err := convertAdmsToGeojson(adm)
-		if err != nil {
-			return err
-		}
-		data, err := json.Marshal(admGeojson)
-		if err != nil {
-			return err
+// Counts adms of bounded queries, unbounded ones are estimated by query
+// planner as counting them takes about as long as streaming them.
+func (service *Service) estimateAdmsCount(ctx context.Context, opts admQueryOpts) (int64, bool, error) {
+	if opts.isBounded() {
+		count, err := service.repo.CountAdms(ctx, opts)
+		return count, true, err
+	}
+	count, err := service.repo.EstimateAdmsCount(ctx, opts)
+	return count, false, err
+}
+
+type geojsonlFeature struct {
+	feature *geojson.Feature
+	data    json.RawMessage
+}
+
+// Errors of the underlying query are returned as well, so that callers can
+// tell a truncated stream from a complete one.
+func (service *Service) getAdmGeojsonlStream(
+	ctx context.Context,
+	ch chan<- geojsonlFeature,
+	opts admQueryOpts,
+) error {
+	defer close(ch)
+
+	return service.writeAdms(ctx, opts, nil, func(ctx context.Context, admCh <-chan Adm) error {
+		for adm := range admCh {
+			feature, err := convertAdmsToGeojson(adm)
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(feature)
+			if err != nil {
+				return fmt.Errorf("failed_to_marshal_feature: adm_id=%s: %w", adm.ID, err)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ch <- geojsonlFeature{feature: feature, data: data}:
+			}
 		}
+		return nil
+	})
+}
 
-		ch <- data
+func (service *Service) GetFlatgeobufColumns(ctx context.Context, opts admQueryOpts) ([]fgbColumn, error) {
+	metadataKeys, err := service.repo.GetAdmMetadataKeys(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return getFgbColumns(metadataKeys), nil
+}
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			continue
+func (service *Service) WriteAdmsFlatgeobuf(
+	ctx context.Context,
+	w io.Writer,
+	opts admQueryOpts,
+	columns []fgbColumn,
+	withIndex bool,
+) error {
+	header := fgbHeader{name: "adm", columns: columns, srid: opts.crs.srid}
+	if opts.lv != nil {
+		header.name = fmt.Sprintf("adm_lv%d", *opts.lv)
+	}
+
+	return service.writeAdms(ctx, opts, nil, func(ctx context.Context, admCh <-chan Adm) error {
+		if withIndex {
+			return writeFgbWithIndex(ctx, w, header, admCh)
 		}
+		return writeFgbStream(ctx, w, header, admCh)
+	})
+}
+
+// Writes every adm of level lv as GeoParquet with WKB geometry and bbox
+// covering column.
+func (service *Service) WriteAdmsGeoparquet(ctx context.Context, w io.Writer, lv int) error {
+	opts, err := NewAdmQueryOptsBuilder().
+		SetLvAndBatchSize(&lv, nil).
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed_to_build_query_options: %w", err)
 	}
+	return service.writeAdmsGeoparquet(ctx, w, opts, nil)
+}
 
-	return nil
+func (service *Service) writeAdmsGeoparquet(
+	ctx context.Context,
+	w io.Writer,
+	opts admQueryOpts,
+	onProgress func(count int64),
+) error {
+	opts.projection = admProjection{geometry: admGeometryBbox}
+	metadataKeys, err := service.repo.GetAdmMetadataKeys(ctx, opts)
+	if err != nil {
+		return err
+	}
+	columns := getGeoparquetColumns(metadataKeys)
+
+	// WKB is queried as for tabular CSV export
+	opts.csvGeometry = csvGeometryWkb
+
+	return service.writeAdms(ctx, opts, onProgress, func(ctx context.Context, admCh <-chan Adm) error {
+		return writeGeoparquet(ctx, w, columns, admCh)
+	})
 }
 
-func (service *Service) GetAdmNeighborsForPoint(ctx context.Context, point utils.Point) ([]Adm, error) {
-	result, err := service.repo.GetAdmForPoint(ctx, point)
+func (service *Service) GetAdmNeighborsForPoint(
+	ctx context.Context,
+	point utils.Point,
+	projection admProjection,
+) ([]Adm, error) {
+	result, err := service.repo.GetAdmForPoint(ctx, point, admProjection{fields: []string{}})
 	if err != nil {
 		return nil, err
 	}
-	neighbors, err := service.repo.GetAdmNeighbors(ctx, result.ID)
+	neighbors, err := service.repo.GetAdmNeighbors(ctx, result.ID, projection)
 	if err != nil {
 		return nil, err
 	}
 	return neighbors, nil
 }
 
+func (service *Service) PopulateAdmNames(ctx context.Context) error {
+	namesCount, err := service.repo.RefreshAdmNames(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info("populate_adm_names_done names=%d", namesCount)
+	return nil
+}
+
 func (service *Service) PopulateAdmTree(ctx context.Context) error {
 	startAfterId := ""
 	batchSize := 20
@@ -0,0 +1,35 @@
+package adm
+
+import "fmt"
+
+type spatialPredicate string
+
+const (
+	spatialPredicateIntersects spatialPredicate = "intersects"
+	// adm geometry lies completely inside the input geometry
+	spatialPredicateWithin spatialPredicate = "within"
+	// adm geometry completely contains the input geometry
+	spatialPredicateContains spatialPredicate = "contains"
+)
+
+func getSpatialPredicateFromString(predicate string) (spatialPredicate, error) {
+	switch spatialPredicate(predicate) {
+	case "":
+		return spatialPredicateIntersects, nil
+	case spatialPredicateIntersects, spatialPredicateWithin, spatialPredicateContains:
+		return spatialPredicate(predicate), nil
+	default:
+		return "", fmt.Errorf("invalid_spatial_predicate: %s", predicate)
+	}
+}
+
+func (predicate spatialPredicate) toSql(admGeom string, inputGeom string) string {
+	switch predicate {
+	case spatialPredicateWithin:
+		return fmt.Sprintf("ST_Within(%s, %s)", admGeom, inputGeom)
+	case spatialPredicateContains:
+		return fmt.Sprintf("ST_Contains(%s, %s)", admGeom, inputGeom)
+	default:
+		return fmt.Sprintf("ST_Intersects(%s, %s)", admGeom, inputGeom)
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"fmt"
 	"gadm-api/logger"
 	"gadm-api/utils"
+	"strings"
 
 	"github.com/Masterminds/squirrel"
 )
 
-func getAdmNeighborsSqlQuery(admId string) (string, []interface{}, error) {
+func getAdmNeighborsSqlQuery(admId string, projection admProjection) (string, []interface{}, error) {
 	withClause := `
 		with ids as (SELECT DISTINCT id FROM adm_neighbors 
 			JOIN ADM ON adm.id=n1 OR adm.id=n2
@@ -16,11 +17,15 @@ func getAdmNeighborsSqlQuery(admId string) (string, []interface{}, error) {
 			OR n2=$1)`
 
 	query := psql.
-		Select("adm.metadata", "adm.id", "adm.lv", "adm.geom_hash").
+		Select(projection.sqlFields()...).
 		Prefix(withClause, admId).
 		From("ids").
 		LeftJoin("adm ON ids.id=adm.id")
 
+	if projection.geometry.needsGeometryTable() {
+		query = query.LeftJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return "", nil, err
@@ -28,7 +33,7 @@ func getAdmNeighborsSqlQuery(admId string) (string, []interface{}, error) {
 	return sql, args, nil
 }
 
-func getAdmForPointSqlQuery(point utils.Point) (string, []interface{}, error) {
+func getAdmForPointSqlQuery(point utils.Point, projection admProjection) (string, []interface{}, error) {
 	withClause := `
 		WITH input_point AS (
 			SELECT ST_SetSRID(ST_MakePoint(?, ?), 4326)::geometry(Point,4326) AS pt
@@ -50,11 +55,16 @@ func getAdmForPointSqlQuery(point utils.Point) (string, []interface{}, error) {
 		)`
 
 	query := psql.
-		Select("adm.metadata", "adm.id", "adm.lv", "adm.geom_hash").
+		Select(projection.sqlFields()...).
 		Prefix(withClause, point.Lng, point.Lat).
 		From("adm").
-		InnerJoin("result_geometry ON adm.geom_hash = result_geometry.geom_hash").
-		Limit(1)
+		InnerJoin("result_geometry ON adm.geom_hash = result_geometry.geom_hash")
+
+	if projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	query = query.Limit(1)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -63,10 +73,365 @@ func getAdmForPointSqlQuery(point utils.Point) (string, []interface{}, error) {
 	return sql, args, nil
 }
 
-func getSelectOneAdmByIdSqlQuery(admId string) (string, []interface{}, error) {
+// Smallest of the geometries nearest to the point, so that like with
+// containment the lowest level sharing the nearest boundary is returned.
+// Distances to a shared boundary differ by float noise, so every geometry
+// within NEAREST_DISTANCE_EPSILON_M of the nearest counts as nearest.
+// Candidates are taken per level, otherwise nested geometries of all levels
+// along the same coastline would crowd out the rest. Point is an outer
+// reference of the lateral subquery, which lets `<->` use the GIST index.
+func getNearestAdmForPointSqlQuery(
+	point utils.Point,
+	maxDistanceM float64,
+	projection admProjection,
+) (string, []interface{}, error) {
+	withClause := `
+		WITH input_point AS (
+			SELECT ST_SetSRID(ST_MakePoint(?, ?), 4326)::geometry(Point,4326) AS pt
+		),
+		candidates AS (
+			SELECT DISTINCT ON (c.geom_hash) c.geom_hash, c.geom, c.area_sq_m
+			FROM input_point ip
+			CROSS JOIN generate_series(0, 5) AS levels(lv)
+			CROSS JOIN LATERAL (
+				SELECT g.geom_hash, g.geom, g.area_sq_m
+				FROM gadm.adm_geometries AS g
+				INNER JOIN gadm.adm AS a ON a.geom_hash = g.geom_hash
+				WHERE a.lv = levels.lv
+				ORDER BY g.geom <-> ip.pt
+				LIMIT ?
+			) AS c
+		),
+		candidate_distances AS (
+			SELECT c.geom_hash, c.area_sq_m, ST_Distance(c.geom::geography, ip.pt::geography) AS distance_m
+			FROM candidates AS c, input_point ip
+			WHERE ST_DWithin(c.geom::geography, ip.pt::geography, ?)
+		),
+		result_geometry AS (
+			SELECT d.geom_hash, d.distance_m
+			FROM candidate_distances AS d
+			WHERE d.distance_m <= (SELECT min(distance_m) FROM candidate_distances) + ?
+			ORDER BY d.area_sq_m ASC
+			LIMIT 1
+		)`
+
 	query := psql.
-		Select("adm.metadata", "adm.id", "adm.lv", "adm.geom_hash").
+		Select(append(projection.sqlFields(), "result_geometry.distance_m")...).
+		Prefix(withClause, point.Lng, point.Lat, NEAREST_ADM_CANDIDATES, maxDistanceM, NEAREST_DISTANCE_EPSILON_M).
+		From("adm").
+		InnerJoin("result_geometry ON adm.geom_hash = result_geometry.geom_hash")
+
+	if projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	query = query.OrderBy("adm.lv DESC").Limit(1)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func getAdmBoundaryDistanceSqlQuery(admId string, point utils.Point) (string, []interface{}, error) {
+	return psql.
+		Select().
+		Column(squirrel.Expr(
+			"ST_Distance(ST_Boundary(g.geom)::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography) AS distance_m",
+			point.Lng, point.Lat,
+		)).
 		From("adm").
+		InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash").
+		Where(squirrel.Eq{"adm.id": admId}).
+		ToSql()
+}
+
+// Adms of given level within tolerance of the point, other than the matched
+// one. Every adm has to be listed, so instead of a capped KNN the GIST index
+// is used by the envelope covering the tolerance, refined by geodesic distance.
+func getAdmsNearPointSqlQuery(
+	point utils.Point,
+	lv int,
+	excludeAdmId string,
+	toleranceM float64,
+	projection admProjection,
+) (string, []interface{}, error) {
+	envelope := getEnvelopeAroundPoint(point, toleranceM)
+	withClause := `
+		WITH input_point AS (
+			SELECT ST_SetSRID(ST_MakePoint(?, ?), 4326)::geometry(Point,4326) AS pt
+		),
+		candidates AS (
+			SELECT a.id, g.geom
+			FROM gadm.adm_geometries AS g
+			INNER JOIN gadm.adm AS a ON a.geom_hash = g.geom_hash
+			WHERE a.lv = ? AND a.id <> ?::uuid
+			AND g.geom && ST_MakeEnvelope(?, ?, ?, ?, 4326)
+		),
+		nearby AS (
+			SELECT c.id, ST_Distance(c.geom::geography, ip.pt::geography) AS distance_m
+			FROM candidates AS c, input_point ip
+			WHERE ST_DWithin(c.geom::geography, ip.pt::geography, ?)
+		)`
+
+	query := psql.
+		Select(append(projection.sqlFields(), "nearby.distance_m")...).
+		Prefix(
+			withClause,
+			point.Lng, point.Lat,
+			lv, excludeAdmId,
+			envelope[0], envelope[1], envelope[2], envelope[3],
+			toleranceM,
+		).
+		From("adm").
+		InnerJoin("nearby ON adm.id = nearby.id")
+
+	if projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	sql, args, err := query.OrderBy("nearby.distance_m ASC", "adm.id").ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func getAdmsForPointsSqlQuery(idxs []int, points []utils.Point, projection admProjection) (string, []interface{}, error) {
+	lngs := make([]float64, len(points))
+	lats := make([]float64, len(points))
+	for i, point := range points {
+		lngs[i] = point.Lng
+		lats[i] = point.Lat
+	}
+
+	withClause := `
+		WITH input_points AS (
+			SELECT p.idx, ST_SetSRID(ST_MakePoint(p.lng, p.lat), 4326)::geometry(Point,4326) AS pt
+			FROM unnest(?::int[], ?::float8[], ?::float8[]) AS p(idx, lng, lat)
+		)`
+
+	query := psql.
+		Select(append([]string{"ip.idx"}, projection.sqlFields()...)...).
+		Prefix(withClause, idxs, lngs, lats).
+		From("input_points ip").
+		JoinClause(`CROSS JOIN LATERAL (
+			SELECT a.id
+			FROM gadm.adm_geometries AS g
+			INNER JOIN gadm.adm AS a ON a.geom_hash = g.geom_hash
+			WHERE ST_Contains(g.geom, ip.pt)
+			ORDER BY g.area_sq_m ASC, a.lv DESC
+			LIMIT 1
+		) AS result`).
+		InnerJoin("gadm.adm ON adm.id = result.id")
+
+	if projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	query = query.OrderBy("ip.idx")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+// Boundaries are intersected rather than polygons, which is cheaper and
+// keeps shared edges of slightly overlapping neighbors or of parent and
+// child adms. Lines and points of intersection are extracted separately.
+func getAdmBorderSqlQuery(admId string, neighborId string) (string, []interface{}, error) {
+	withClause := `
+		WITH pair AS (
+			SELECT
+				a.id,
+				b.id AS neighbor_id,
+				CASE WHEN ga.geom && gb.geom
+					THEN ST_Intersection(ST_Boundary(ga.geom), ST_Boundary(gb.geom))
+					ELSE 'GEOMETRYCOLLECTION EMPTY'::geometry
+				END AS geom
+			FROM gadm.adm AS a
+			INNER JOIN gadm.adm_geometries AS ga ON a.geom_hash = ga.geom_hash
+			CROSS JOIN gadm.adm AS b
+			INNER JOIN gadm.adm_geometries AS gb ON b.geom_hash = gb.geom_hash
+			WHERE a.id = ?::uuid AND b.id = ?::uuid
+		),
+		parts AS (
+			SELECT
+				id,
+				neighbor_id,
+				ST_LineMerge(ST_CollectionExtract(geom, 2)) AS lines,
+				ST_CollectionExtract(geom, 1) AS points
+			FROM pair
+		)`
+
+	return psql.
+		Select(
+			"id",
+			"neighbor_id",
+			fmt.Sprintf(`CASE
+				WHEN NOT ST_IsEmpty(lines) THEN '%s'
+				WHEN NOT ST_IsEmpty(points) THEN '%s'
+				ELSE '%s'
+			END AS contact`, admContactBorder, admContactPoint, admContactNone),
+			"ST_Length(lines::geography) AS length_m",
+			`CASE
+				WHEN NOT ST_IsEmpty(lines) THEN ST_AsGeoJSON(lines, 6)
+				WHEN NOT ST_IsEmpty(points) THEN ST_AsGeoJSON(points, 6)
+			END AS geometry`,
+		).
+		Prefix(withClause, admId, neighborId).
+		From("parts").
+		ToSql()
+}
+
+// Convex hull is planar in lon/lat, its area is overstated for adms that
+// cross the antimeridian.
+func getAdmStatsSqlQuery(admId string) (string, []interface{}, error) {
+	return psql.
+		Select(
+			"adm.id",
+			"adm.lv",
+			admGidSqlField,
+			admNameSqlField,
+			"g.area_sq_m",
+			"ST_Perimeter(g.geom::geography) AS perimeter_m",
+			`ARRAY[
+				round(ST_X(c.centroid)::numeric, 6)::float8,
+				round(ST_Y(c.centroid)::numeric, 6)::float8
+			] AS centroid`,
+			`ARRAY[
+				round(ST_X(c.point_on_surface)::numeric, 6)::float8,
+				round(ST_Y(c.point_on_surface)::numeric, 6)::float8
+			] AS point_on_surface`,
+			admBboxSqlField,
+			"ST_NumGeometries(g.geom) AS parts_count",
+			"(SELECT COALESCE(sum(ST_NumInteriorRings(d.geom)), 0) FROM ST_Dump(g.geom) AS d)::int AS holes_count",
+			"ST_NPoints(g.geom) AS vertex_count",
+			"(SELECT count(*) FROM gadm.adm_tree t WHERE t.parent = adm.id AND t.child IS NOT NULL) AS children_count",
+			"(SELECT count(*) FROM gadm.adm_neighbors n WHERE n.n1 = adm.id OR n.n2 = adm.id) AS neighbors_count",
+			"ST_Area(ST_ConvexHull(g.geom)::geography) AS convex_hull_area_sq_m",
+		).
+		From("gadm.adm").
+		InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash").
+		JoinClause(`CROSS JOIN LATERAL (
+			SELECT
+				ST_Centroid(g.geom::geography)::geometry AS centroid,
+				ST_PointOnSurface(g.geom) AS point_on_surface
+		) AS c`).
+		Where("adm.id = ?::uuid", admId).
+		ToSql()
+}
+
+func getAdmAncestrySqlQuery(admId string, includeGeometry bool) (string, []interface{}, error) {
+	withClause := `
+		WITH RECURSIVE ancestry AS (
+			SELECT ?::uuid AS id
+			UNION
+			SELECT t.parent
+			FROM ancestry a
+			INNER JOIN gadm.adm_tree t ON t.child = a.id
+		)`
+
+	fields := []string{"adm.id", "adm.lv", admGidSqlField, admNameSqlField}
+	if includeGeometry {
+		fields = append(fields, "ST_AsGeoJSON(g.geom, 6) as geom")
+	}
+
+	query := psql.
+		Select(fields...).
+		Prefix(withClause, admId).
+		From("ancestry").
+		InnerJoin("gadm.adm ON adm.id = ancestry.id")
+
+	if includeGeometry {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	query = query.OrderBy("adm.lv ASC")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func getSelectAdmRelativesSqlQuery(
+	admId string,
+	relation admRelation,
+	depth int,
+	options admQueryOpts,
+) (string, []interface{}, error) {
+	// adm_tree stores leaf adms with NULL child
+	withClause := `
+		WITH RECURSIVE relatives AS (
+			SELECT t.child AS id, 1 AS depth
+			FROM gadm.adm_tree t
+			WHERE t.parent = ?::uuid AND t.child IS NOT NULL
+			UNION
+			SELECT t.child, r.depth + 1
+			FROM relatives r
+			INNER JOIN gadm.adm_tree t ON t.parent = r.id
+			WHERE t.child IS NOT NULL AND r.depth < ?
+		)`
+	if relation.isUpwards() {
+		withClause = `
+			WITH RECURSIVE relatives AS (
+				SELECT t.parent AS id, 1 AS depth
+				FROM gadm.adm_tree t
+				WHERE t.child = ?::uuid
+				UNION
+				SELECT t.parent, r.depth + 1
+				FROM relatives r
+				INNER JOIN gadm.adm_tree t ON t.child = r.id
+				WHERE r.depth < ?
+			)`
+	}
+
+	query := psql.
+		Select(options.projection.sqlFields()...).
+		Prefix(withClause, admId, depth).
+		From("relatives").
+		InnerJoin("gadm.adm ON adm.id = relatives.id")
+
+	if options.projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	if options.startAfterId != nil {
+		query = query.Where("adm.id > ?", *options.startAfterId)
+	}
+
+	if options.lv != nil {
+		query = query.Where("adm.lv = ?", *options.lv)
+	}
+
+	query = query.OrderBy("adm.id")
+
+	if options.batchSize != nil {
+		query = query.Limit(uint64(*options.batchSize))
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func getSelectOneAdmByIdSqlQuery(admId string, projection admProjection) (string, []interface{}, error) {
+	query := psql.
+		Select(projection.sqlFields()...).
+		From("adm")
+
+	if projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	query = query.
 		Where("adm.id = $1", admId).
 		Limit(1)
 
@@ -77,27 +442,207 @@ func getSelectOneAdmByIdSqlQuery(admId string) (string, []interface{}, error) {
 	return sql, args, nil
 }
 
-func getSelectAdmsSqlQuery(options admQueryOpts) (string, []interface{}, error) {
-	fields := []string{"adm.metadata", "adm.id", "adm.lv", "adm.geom_hash"}
-	if options.includeGeometry {
-		fields = append(
-			fields,
-			"ST_AsGeoJSON(g.geom, 6) as geom",
-			`ARRAY[ 
-				ST_XMin(g.bbox), 
-				ST_YMin(g.bbox), 
-				ST_XMax(g.bbox), 
-				ST_YMax(g.bbox)
-			] as bbox`,
+const admGidSqlField = "adm.metadata ->> ('gid_' || adm.lv::text) AS gid"
+
+// level 0 rows have no name_0 key, country name is stored under 'country'
+const admNameSqlField = `COALESCE(
+	adm.metadata ->> ('name_' || adm.lv::text),
+	adm.metadata ->> 'country'
+) AS name`
+
+// names of ancestors, selected apart from metadata which may be projected
+const admBreadcrumbNamesSqlField = `jsonb_build_object(
+	'country', adm.metadata -> 'country',
+	'name_1', adm.metadata -> 'name_1',
+	'name_2', adm.metadata -> 'name_2',
+	'name_3', adm.metadata -> 'name_3',
+	'name_4', adm.metadata -> 'name_4'
+) AS breadcrumb_names`
+
+const admBboxSqlField = `ARRAY[ 
+	ST_XMin(g.bbox), 
+	ST_YMin(g.bbox), 
+	ST_XMax(g.bbox), 
+	ST_YMax(g.bbox)
+] as bbox`
+
+// Lookup expressions must match the expression indexes on gadm.adm
+// (idx_adm_gid, idx_adm_hasc, idx_adm_iso_1) so that they are used.
+func getSelectAdmsByIdentifiersSqlQuery(
+	idType admIdType,
+	ids []string,
+	projection admProjection,
+) (string, []interface{}, error) {
+	gidExpr := "adm.metadata ->> ('gid_' || adm.lv::text)"
+	hascExpr := "adm.metadata ->> ('hasc_' || adm.lv::text)"
+	iso1Expr := "adm.metadata ->> 'iso_1'"
+
+	query := psql.
+		Select(projection.sqlFields()...).
+		From("gadm.adm")
+
+	if projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+	}
+
+	switch idType {
+	case admIdTypeId:
+		query = query.
+			Column("adm.id::text AS lookup_key").
+			Where("adm.id = ANY(?::uuid[])", ids)
+	case admIdTypeIso:
+		query = query.
+			Column(fmt.Sprintf("CASE WHEN adm.lv = 0 THEN %s ELSE %s END AS lookup_key", gidExpr, iso1Expr)).
+			Where(fmt.Sprintf(
+				"(adm.lv = 0 AND %s = ANY(?::text[])) OR (adm.lv = 1 AND %s = ANY(?::text[]))",
+				gidExpr, iso1Expr,
+			), ids, ids)
+	case admIdTypeHasc:
+		query = query.
+			Column(hascExpr+" AS lookup_key").
+			Where("adm.lv BETWEEN 1 AND 3").
+			Where(hascExpr+" = ANY(?::text[])", ids)
+	default:
+		query = query.
+			Column(gidExpr+" AS lookup_key").
+			Where(gidExpr+" = ANY(?::text[])", ids)
+	}
+
+	query = query.OrderBy("adm.lv", "adm.id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+// Names are matched against gadm.adm_names which holds name, variant names
+// and local names of every adm normalized by gadm.normalize_adm_name.
+// Both operators below are served by the trigram GIN index.
+func getSearchAdmsSqlQuery(
+	searchQuery string,
+	lv *int,
+	country string,
+	limit int,
+	projection admProjection,
+) (string, []interface{}, error) {
+	withClause := `
+		WITH search AS (
+			SELECT gadm.normalize_adm_name(?::text) AS q
+		)`
+
+	query := psql.
+		Select(append(
+			projection.sqlFields(),
+			admGidSqlField,
+			admNameSqlField,
+			admBreadcrumbNamesSqlField,
+			"(array_agg(n.name ORDER BY s.score DESC))[1] AS matched_name",
+			"max(s.score) AS score",
+		)...).
+		Prefix(withClause, searchQuery).
+		From("search").
+		InnerJoin("gadm.adm_names n ON (n.normalized_name % search.q OR search.q <% n.normalized_name)").
+		InnerJoin("gadm.adm ON adm.id = n.adm_id").
+		JoinClause(`CROSS JOIN LATERAL (
+			SELECT GREATEST(
+				similarity(n.normalized_name, search.q),
+				word_similarity(search.q, n.normalized_name)
+			) AS score
+		) AS s`)
+
+	// geometry columns depend on primary key of adm_geometries
+	groupBy := []string{"adm.id"}
+	if projection.geometry.needsGeometryTable() {
+		query = query.InnerJoin("gadm.adm_geometries g ON adm.geom_hash = g.geom_hash")
+		groupBy = append(groupBy, "g.geom_hash")
+	}
+
+	if lv != nil {
+		query = query.Where("adm.lv = ?", *lv)
+	}
+
+	if country != "" {
+		query = query.Where("adm.metadata ->> 'gid_0' = ?", country)
+	}
+
+	query = query.
+		GroupBy(groupBy...).
+		OrderBy("score DESC", "adm.id").
+		Limit(uint64(limit))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+// Bbox is computed from geometry transformed into the requested crs since
+// transforming corners of the stored bbox does not give a correct envelope.
+func getTransformedGeometryFields(options admQueryOpts) []string {
+	if options.crs.isDefault() {
+		return options.projection.geometrySqlFields("g.geom", admBboxSqlField, options.precision)
+	}
+	return options.projection.geometrySqlFields(
+		"tg.geom",
+		`ARRAY[
+			ST_XMin(tg.geom),
+			ST_YMin(tg.geom),
+			ST_XMax(tg.geom),
+			ST_YMax(tg.geom)
+		] as bbox`,
+		options.precision,
+	)
+}
+
+// WKT and WKB are in the requested crs, centroid is always lat/lng.
+func getCsvFields(options admQueryOpts) []string {
+	geom := "g.geom"
+	if !options.crs.isDefault() {
+		geom = "tg.geom"
+	}
+
+	fields := []string{}
+	if options.includeArea {
+		fields = append(fields, "g.area_sq_m")
+	}
+	switch options.csvGeometry {
+	case csvGeometryWkt:
+		fields = append(fields, fmt.Sprintf("ST_AsText(%s, %d) AS wkt", geom, options.precision))
+	case csvGeometryWkb:
+		fields = append(fields, fmt.Sprintf("encode(ST_AsBinary(%s), 'hex') AS wkb", geom))
+	case csvGeometryCentroid:
+		fields = append(fields,
+			fmt.Sprintf("round(ST_Y(ST_Centroid(g.geom))::numeric, %d)::float8 AS centroid_lat", options.precision),
+			fmt.Sprintf("round(ST_X(ST_Centroid(g.geom))::numeric, %d)::float8 AS centroid_lng", options.precision),
 		)
 	}
+	return fields
+}
+
+func getSelectAdmsSqlQuery(options admQueryOpts) (string, []interface{}, error) {
+	sql, args, err := getSelectAdmsQuery(options).ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func getSelectAdmsQuery(options admQueryOpts) squirrel.SelectBuilder {
+	fields := options.projection.admSqlFields()
+	fields = append(fields, getTransformedGeometryFields(options)...)
+	fields = append(fields, getCsvFields(options)...)
+	if options.sort.needsSortValue() {
+		fields = append(fields, options.sort.sqlExpr()+" AS sort_value")
+	}
 	query := psql.
 		Select(fields...).
 		From("adm")
 
 	if options.startAfterId != nil {
 		query = query.Where("adm.id > ?", *options.startAfterId)
-		query = query.OrderBy("adm.id")
 	}
 
 	if options.lv != nil {
@@ -108,13 +653,201 @@ func getSelectAdmsSqlQuery(options admQueryOpts) (string, []interface{}, error)
 		}
 	}
 
-	if options.includeGeometry {
+	needsGeometryTable := options.projection.geometry.needsGeometryTable() || options.hasCsvGeometry()
+	if needsGeometryTable || options.bbox != nil || options.includeArea || options.sort.key == admSortArea {
 		query = query.Join("adm_geometries g on adm.geom_hash = g.geom_hash")
 	}
 
+	if needsGeometryTable && !options.crs.isDefault() {
+		query = query.JoinClause(fmt.Sprintf(
+			"CROSS JOIN LATERAL (SELECT %s AS geom) AS tg",
+			options.crs.transformSql("g.geom"),
+		))
+	}
+
+	if options.bbox != nil {
+		query = query.Where(getBboxFilterSql(*options.bbox))
+	}
+
+	if options.country != nil {
+		query = query.Where("adm.metadata ->> 'gid_0' = ?", *options.country)
+	}
+
+	if options.parentId != nil {
+		query = query.Where(getParentFilterSql(*options.parentId))
+	}
+
+	if options.id != nil {
+		query = query.Where("adm.id = ?::uuid", *options.id)
+	}
+
 	if options.startAfterFid != nil {
 		query = query.Where(squirrel.Gt{"adm.metadata ->> 'fid'": *options.startAfterFid})
 		query = query.OrderBy("adm.metadata ->> 'fid'")
+	} else {
+		query = applyAdmSort(query, options.sort, options.keyset)
+	}
+
+	if options.batchSize != nil {
+		query = query.Limit(uint64(*options.batchSize))
+	}
+
+	return query
+}
+
+const ADMS_CURSOR_NAME = "adms_cursor"
+
+// Rows fetched at once, geometries of low levels can take megabytes each.
+const ADMS_CURSOR_FETCH_SIZE = 50
+
+func getDeclareAdmsCursorSqlQuery(options admQueryOpts) (string, []interface{}, error) {
+	return getSelectAdmsQuery(options).
+		Prefix(fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR", ADMS_CURSOR_NAME)).
+		ToSql()
+}
+
+func getFetchAdmsCursorSqlQuery() string {
+	return fmt.Sprintf("FETCH FORWARD %d FROM %s", ADMS_CURSOR_FETCH_SIZE, ADMS_CURSOR_NAME)
+}
+
+// Orders by sort expression and id, keyset selects adms past the boundary
+// by row comparison so that ties in sort value are paged through by id.
+func applyAdmSort(query squirrel.SelectBuilder, sort admSort, keyset *admKeyset) squirrel.SelectBuilder {
+	desc := sort.desc
+	if keyset != nil && keyset.backward {
+		desc = !desc
+	}
+	op, direction := ">", ""
+	if desc {
+		op, direction = "<", " DESC"
+	}
+
+	if keyset != nil {
+		if sort.needsSortValue() {
+			query = query.Where(fmt.Sprintf("(%s, adm.id) %s (?, ?::uuid)", sort.sqlExpr(), op), keyset.sortValue, keyset.id)
+		} else {
+			query = query.Where(fmt.Sprintf("adm.id %s ?::uuid", op), keyset.id)
+		}
+	}
+
+	if sort.needsSortValue() {
+		return query.OrderBy(sort.sqlExpr()+direction, "adm.id"+direction)
+	}
+	return query.OrderBy("adm.id" + direction)
+}
+
+// Same filters as getSelectAdmsQuery without any geometry work.
+func getSelectAdmIdsQuery(options admQueryOpts) squirrel.SelectBuilder {
+	options.projection = admProjection{geometry: admGeometryNone}
+	options.csvGeometry = ""
+	options.includeArea = false
+	options.batchSize = nil
+
+	return getSelectAdmsQuery(options).RemoveColumns().Columns("adm.id")
+}
+
+func getCountAdmsSqlQuery(options admQueryOpts) (string, []interface{}, error) {
+	return psql.
+		Select("count(*)").
+		FromSelect(getSelectAdmIdsQuery(options), "a").
+		ToSql()
+}
+
+// Planner estimate of number of adms, cheap compared to counting them.
+func getEstimateAdmsCountSqlQuery(options admQueryOpts) (string, []interface{}, error) {
+	return getSelectAdmIdsQuery(options).
+		Prefix("EXPLAIN (FORMAT JSON)").
+		ToSql()
+}
+
+// Distinct jsonb types of every metadata key of adms matching the options,
+// integral numbers are reported as "integer".
+func getAdmMetadataKeysSqlQuery(options admQueryOpts) (string, []interface{}, error) {
+	options.projection.geometry = admGeometryNone
+	options.csvGeometry = ""
+	options.includeArea = false
+	options.batchSize = nil
+
+	return psql.
+		Select(
+			"e.key AS name",
+			`array_agg(DISTINCT CASE
+				WHEN jsonb_typeof(e.value) = 'number' AND e.value::numeric = trunc(e.value::numeric) THEN 'integer'
+				ELSE jsonb_typeof(e.value)
+			END) AS types`,
+		).
+		FromSelect(getSelectAdmsQuery(options), "a").
+		JoinClause("CROSS JOIN LATERAL jsonb_each(a.metadata) AS e").
+		GroupBy("e.key").
+		OrderBy("e.key").
+		ToSql()
+}
+
+// Bbox overlap is answered by idx_adm_geometries_bbox, exact intersection
+// test is only run for the remaining candidates.
+func getBboxFilterSql(b bbox) squirrel.Sqlizer {
+	minx, miny, maxx, maxy := b[0], b[1], b[2], b[3]
+	if minx <= maxx {
+		return squirrel.Expr(
+			`(g.bbox && ST_MakeEnvelope(?, ?, ?, ?, 4326)
+			AND ST_Intersects(g.geom, ST_MakeEnvelope(?, ?, ?, ?, 4326)))`,
+			minx, miny, maxx, maxy, minx, miny, maxx, maxy,
+		)
+	}
+	return squirrel.Or{
+		getBboxFilterSql(bbox{minx, miny, 180, maxy}),
+		getBboxFilterSql(bbox{-180, miny, maxx, maxy}),
+	}
+}
+
+// Descendants are matched by parent's GID stored in their metadata instead
+// of adm_tree so that the filter does not depend on derived tables.
+func getParentFilterSql(parentId string) squirrel.Sqlizer {
+	return squirrel.Expr(`EXISTS (
+		SELECT 1 FROM gadm.adm AS parent
+		WHERE parent.id = ?::uuid
+		AND adm.lv > parent.lv
+		AND adm.metadata ->> ('gid_' || parent.lv::text) = parent.metadata ->> ('gid_' || parent.lv::text)
+	)`, parentId)
+}
+
+func getSelectAdmsForGeometrySqlQuery(
+	geometry string,
+	predicate spatialPredicate,
+	options admQueryOpts,
+) (string, []interface{}, error) {
+	withClause := `
+		WITH input_geometry AS (
+			SELECT geom, ST_Area(geom::geography) AS area_sq_m
+			FROM (
+				SELECT ST_MakeValid(ST_SetSRID(ST_GeomFromGeoJSON(?::text), 4326)) AS geom
+			) AS ig
+		)`
+
+	fields := append(
+		options.projection.sqlFields(),
+		"overlap.area_sq_m AS overlap_area_sq_m",
+		"overlap.area_sq_m / NULLIF(ig.area_sq_m, 0) AS overlap_fraction_of_input",
+		"overlap.area_sq_m / NULLIF(g.area_sq_m, 0) AS overlap_fraction_of_adm",
+	)
+
+	query := psql.
+		Select(fields...).
+		Prefix(withClause, geometry).
+		From("input_geometry ig").
+		InnerJoin("gadm.adm_geometries g ON " + predicate.toSql("g.geom", "ig.geom")).
+		InnerJoin("gadm.adm ON adm.geom_hash = g.geom_hash").
+		JoinClause(`CROSS JOIN LATERAL (
+			SELECT ST_Area(ST_Intersection(g.geom, ig.geom)::geography) AS area_sq_m
+		) AS overlap`).
+		OrderBy("adm.id")
+
+	if options.lv != nil {
+		query = query.Where("adm.lv = ?", *options.lv)
+	}
+
+	if options.startAfterId != nil {
+		query = query.Where("adm.id > ?", *options.startAfterId)
 	}
 
 	if options.batchSize != nil {
@@ -128,6 +861,69 @@ func getSelectAdmsSqlQuery(options admQueryOpts) (string, []interface{}, error)
 	return sql, args, nil
 }
 
+// Every level is encoded as a separate MVT layer named lv<N>, layers are
+// concatenated into a single tile. Geometries are clipped in EPSG:4326
+// before being transformed so that large adms are not reprojected whole.
+func getAdmTileSqlQuery(tile tileCoords, levels []int) (string, []interface{}, error) {
+	withClause := `
+		WITH bounds AS (
+			SELECT
+				ST_TileEnvelope(?, ?, ?) AS geom,
+				ST_Transform(ST_TileEnvelope(?, ?, ?, margin => ?), 4326) AS clip_geom
+		)`
+
+	layers := make([]string, len(levels))
+	for i, lv := range levels {
+		layers[i] = fmt.Sprintf(`COALESCE((
+			SELECT ST_AsMVT(layer, 'lv%d', %d, 'geom')
+			FROM (
+				SELECT
+					adm.id::text AS id,
+					%s,
+					%s,
+					adm.lv,
+					ST_AsMVTGeom(
+						ST_Transform(ST_ClipByBox2D(g.geom, bounds.clip_geom), 3857),
+						bounds.geom,
+						%d,
+						%d,
+						true
+					) AS geom
+				FROM bounds
+				INNER JOIN gadm.adm_geometries g ON g.geom && bounds.clip_geom
+				INNER JOIN gadm.adm ON adm.geom_hash = g.geom_hash
+				WHERE adm.lv = %d
+			) AS layer
+			WHERE layer.geom IS NOT NULL
+		), ''::bytea)`,
+			lv, MVT_EXTENT, admGidSqlField, admNameSqlField, MVT_EXTENT, MVT_BUFFER, lv)
+	}
+
+	margin := float64(MVT_BUFFER) / float64(MVT_EXTENT)
+	query := psql.
+		Select(strings.Join(layers, " || ")+" AS tile").
+		Prefix(withClause, tile.z, tile.x, tile.y, tile.z, tile.x, tile.y, margin)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
+func getSpatialRefSysSqlQuery(srid int) (string, []interface{}, error) {
+	query := psql.
+		Select("srtext LIKE 'GEOGCS%' AS is_geographic").
+		From("spatial_ref_sys").
+		Where("srid = ?", srid)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, args, nil
+}
+
 func getUpsertAdmTreeSqlQuery(parentId string, childIds []string) (string, []interface{}, error) {
 	if len(childIds) == 0 {
 		return "", nil, nil
@@ -188,6 +984,28 @@ func getNeighborsSqlQuery(admId string) (string, []interface{}, error) {
 	return sql, args, nil
 }
 
+// Both directions of neighbor relations of given adms, leading to adms of
+// given level.
+func getAdmNeighborEdgesSqlQuery(ids []string, lv int) (string, []interface{}, error) {
+	return psql.
+		Select("n.n1::text AS id", "n.n2::text AS neighbor_id").
+		From("gadm.adm_neighbors n").
+		InnerJoin("gadm.adm ON adm.id = n.n2").
+		Where("n.n1 = ANY(?::uuid[])", ids).
+		Where(squirrel.Eq{"adm.lv": lv}).
+		Suffix(`UNION ALL
+			SELECT n.n2::text AS id, n.n1::text AS neighbor_id
+			FROM gadm.adm_neighbors n
+			INNER JOIN gadm.adm ON adm.id = n.n1
+			WHERE n.n2 = ANY(?::uuid[]) AND adm.lv = ?
+			ORDER BY id, neighbor_id`, ids, lv).
+		ToSql()
+}
+
+func getRefreshAdmNamesSqlQuery() (string, []interface{}, error) {
+	return psql.Select("gadm.refresh_adm_names()").ToSql()
+}
+
 func getSelectLeafAdmsSqlQuery(startAfterId string, batchSize int) (string, []interface{}, error) {
 	query := psql.
 		Select("adm.metadata", "adm.id", "adm.lv", "adm.geom_hash").
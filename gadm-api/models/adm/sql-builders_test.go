@@ -0,0 +1,33 @@
+package adm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetDeclareAdmsCursorSqlQuery(t *testing.T) {
+	t.Logf("Test: getDeclareAdmsCursorSqlQuery - declares cursor over unlimited select")
+
+	lv := 5
+	opts, _ := NewAdmQueryOptsBuilder().
+		SetLvAndBatchSize(&lv, nil).
+		SetProjection(admProjection{geometry: admGeometryFull}).
+		Build()
+	sql, args, err := getDeclareAdmsCursorSqlQuery(opts)
+	if err != nil {
+		t.Fatalf("failed to build query: %v", err)
+	}
+
+	if !strings.HasPrefix(sql, "DECLARE adms_cursor NO SCROLL CURSOR FOR SELECT ") {
+		t.Errorf("unexpected query prefix: %s", sql)
+	}
+	if !strings.HasSuffix(sql, "ORDER BY adm.id") {
+		t.Errorf("expected query ordered by id without limit: %s", sql)
+	}
+	if len(args) != 1 || args[0] != 5 {
+		t.Errorf("unexpected args: %v", args)
+	}
+	if getFetchAdmsCursorSqlQuery() != "FETCH FORWARD 50 FROM adms_cursor" {
+		t.Errorf("unexpected fetch query: %s", getFetchAdmsCursorSqlQuery())
+	}
+}
@@ -0,0 +1,171 @@
+package adm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gadm-api/logger"
+
+	"github.com/google/uuid"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const SSE_PROGRESS_INTERVAL = time.Second
+
+// Comment lines keep idle connections open behind proxies.
+const SSE_HEARTBEAT_INTERVAL = 15 * time.Second
+
+const (
+	sseEventFeature     = "feature"
+	sseEventProgress    = "progress"
+	sseEventEnd         = "end"
+	sseEventInterrupted = "interrupted"
+)
+
+type sseProgress struct {
+	Sent int64 `json:"sent"`
+	// null until estimated, with total_is_estimate it comes from query planner
+	Total           *int64 `json:"total"`
+	TotalIsEstimate bool   `json:"total_is_estimate"`
+}
+
+func writeSseEvent(f *flusher, event string, id string, data []byte) error {
+	var b bytes.Buffer
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", event, data)
+	return f.flushRaw(b.Bytes())
+}
+
+func writeSseJsonEvent(f *flusher, event string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed_to_marshal_sse_event: %w", err)
+	}
+	return writeSseEvent(f, event, "", data)
+}
+
+// Streams adms as Server-Sent Events. Features are sent as `feature` events
+// with adm id as event id, so that EventSource reconnecting after a dropped
+// connection resumes from it with Last-Event-ID header. Completed stream
+// ends with `end` event, after which client should close the EventSource.
+func (handler *Handler) AdmGeojsonlSseHandler(w http.ResponseWriter, r *http.Request, baseUrl url.URL) {
+	opts, err := handler.getAdmQueryOptsFromRequest(r)
+	if err != nil {
+		writeAdmQueryOptsError(w, err)
+		return
+	}
+	// resumption is keyed on adm id, so the stream is ordered by it
+	if opts.startAfterFid != nil {
+		http.Error(w, "start_after_fid_not_supported", http.StatusBadRequest)
+		return
+	}
+	if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+		if _, err := uuid.Parse(lastEventId); err != nil {
+			logger.Error("invalid_last_event_id id=%s", lastEventId)
+			http.Error(w, "invalid_request", http.StatusBadRequest)
+			return
+		}
+		opts.startAfterId = &lastEventId
+	}
+
+	flusher, err := newFlusher(r.Context(), w)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ch := make(chan geojsonlFeature, 10)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- handler.service.getAdmGeojsonlStream(ctx, ch, opts)
+	}()
+
+	progress := sseProgress{}
+	totalCh := make(chan sseProgress, 1)
+	go func() {
+		total, exact, err := handler.service.estimateAdmsCount(ctx, opts)
+		if err != nil {
+			logger.Error("failed_to_estimate_adms_count %v", err)
+			return
+		}
+		totalCh <- sseProgress{Total: &total, TotalIsEstimate: !exact}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Content-Crs", fmt.Sprintf("<%s>", opts.crs.uri))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disables response buffering in nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.flusher.Flush()
+
+	progressTicker := time.NewTicker(SSE_PROGRESS_INTERVAL)
+	defer progressTicker.Stop()
+	heartbeatTicker := time.NewTicker(SSE_HEARTBEAT_INTERVAL)
+	defer heartbeatTicker.Stop()
+
+	var lastFeature *geojson.Feature
+	var writeErr error
+	progressChanged := false
+	for ch != nil {
+		select {
+		case feature, ok := <-ch:
+			if !ok {
+				ch = nil
+				continue
+			}
+			if writeErr != nil {
+				continue
+			}
+			writeErr = writeSseEvent(flusher, sseEventFeature, fmt.Sprint(feature.feature.ID), feature.data)
+			lastFeature = feature.feature
+			progress.Sent++
+			progressChanged = true
+		case total := <-totalCh:
+			progress.Total = total.Total
+			progress.TotalIsEstimate = total.TotalIsEstimate
+			progressChanged = true
+		case <-progressTicker.C:
+			if writeErr == nil && progressChanged {
+				writeErr = writeSseJsonEvent(flusher, sseEventProgress, progress)
+				progressChanged = false
+			}
+		case <-heartbeatTicker.C:
+			if writeErr == nil {
+				writeErr = flusher.flushRaw([]byte(": heartbeat\n\n"))
+			}
+		}
+		if writeErr != nil {
+			cancel()
+		}
+	}
+	streamErr := <-errCh
+	if writeErr != nil {
+		logger.Error("failed_to_write_adm_sse sent=%d %v", progress.Sent, writeErr)
+		return
+	}
+
+	if progressChanged {
+		if err := writeSseJsonEvent(flusher, sseEventProgress, progress); err != nil {
+			return
+		}
+	}
+	trailer := newStreamTrailer(baseUrl, opts, lastFeature, progress.Sent, streamErr)
+	if streamErr != nil {
+		// client reconnects with Last-Event-ID once connection is closed
+		logger.Error("failed_to_get_adm_sse sent=%d %v", progress.Sent, streamErr)
+		writeSseJsonEvent(flusher, sseEventInterrupted, trailer)
+		return
+	}
+	writeSseJsonEvent(flusher, sseEventEnd, trailer)
+}
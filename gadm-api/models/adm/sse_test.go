@@ -0,0 +1,31 @@
+package adm
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteSseEvent(t *testing.T) {
+	t.Logf("Test: writeSseEvent - event with and without id")
+
+	recorder := httptest.NewRecorder()
+	f, err := newFlusher(context.Background(), recorder)
+	if err != nil {
+		t.Fatalf("failed to create flusher: %v", err)
+	}
+
+	if err := writeSseEvent(f, sseEventFeature, "b3c1", []byte(`{"type":"Feature"}`)); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+	total := int64(20)
+	if err := writeSseJsonEvent(f, sseEventProgress, sseProgress{Sent: 10, Total: &total}); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+
+	expected := "id: b3c1\nevent: feature\ndata: {\"type\":\"Feature\"}\n\n" +
+		"event: progress\ndata: {\"sent\":10,\"total\":20,\"total_is_estimate\":false}\n\n"
+	if recorder.Body.String() != expected {
+		t.Errorf("unexpected events: %q. Expected %q", recorder.Body.String(), expected)
+	}
+}
@@ -0,0 +1,45 @@
+package adm
+
+import "math"
+
+// Measures are geodesic, coordinates are in CRS84.
+type admStats struct {
+	ID             string    `db:"id" json:"id"`
+	Level          int       `db:"lv" json:"lv"`
+	Gid            *string   `db:"gid" json:"gid"`
+	Name           *string   `db:"name" json:"name"`
+	AreaSqM        float64   `db:"area_sq_m" json:"area_sq_m"`
+	PerimeterM     float64   `db:"perimeter_m" json:"perimeter_m"`
+	Centroid       []float64 `db:"centroid" json:"centroid"`
+	PointOnSurface []float64 `db:"point_on_surface" json:"point_on_surface"`
+	Bbox           []float64 `db:"bbox" json:"bbox"`
+	PartsCount     int       `db:"parts_count" json:"parts_count"`
+	HolesCount     int       `db:"holes_count" json:"holes_count"`
+	VertexCount    int       `db:"vertex_count" json:"vertex_count"`
+	ChildrenCount  int       `db:"children_count" json:"children_count"`
+	NeighborsCount int       `db:"neighbors_count" json:"neighbors_count"`
+
+	ConvexHullAreaSqM float64        `db:"convex_hull_area_sq_m" json:"convex_hull_area_sq_m"`
+	Compactness       admCompactness `db:"-" json:"compactness"`
+}
+
+// Both metrics approach 0 for ragged shapes. Polsby-Popper is 1 only for
+// a circle and also drops for elongated shapes, convex hull ratio is 1 for
+// any convex shape.
+type admCompactness struct {
+	PolsbyPopper    *float64 `json:"polsby_popper"`
+	ConvexHullRatio *float64 `json:"convex_hull_ratio"`
+}
+
+func getAdmCompactness(areaSqM float64, perimeterM float64, convexHullAreaSqM float64) admCompactness {
+	compactness := admCompactness{}
+	if perimeterM > 0 {
+		polsbyPopper := 4 * math.Pi * areaSqM / (perimeterM * perimeterM)
+		compactness.PolsbyPopper = &polsbyPopper
+	}
+	if convexHullAreaSqM > 0 {
+		convexHullRatio := math.Min(areaSqM/convexHullAreaSqM, 1)
+		compactness.ConvexHullRatio = &convexHullRatio
+	}
+	return compactness
+}
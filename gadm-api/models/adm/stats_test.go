@@ -0,0 +1,31 @@
+package adm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetAdmCompactness(t *testing.T) {
+	t.Logf("Test: getAdmCompactness - Polsby-Popper and convex hull ratio")
+
+	r := 1000.0
+	circle := getAdmCompactness(math.Pi*r*r, 2*math.Pi*r, math.Pi*r*r)
+	if math.Abs(*circle.PolsbyPopper-1) > 1e-9 || *circle.ConvexHullRatio != 1 {
+		t.Errorf("expected compactness of circle to be 1, got %v %v", *circle.PolsbyPopper, *circle.ConvexHullRatio)
+	}
+
+	// square with a quarter cut out, hull is the full square
+	side := 100.0
+	lShape := getAdmCompactness(0.75*side*side, 4*side, side*side)
+	if math.Abs(*lShape.PolsbyPopper-0.75*math.Pi/4) > 1e-9 {
+		t.Errorf("unexpected Polsby-Popper of L shape: %v", *lShape.PolsbyPopper)
+	}
+	if math.Abs(*lShape.ConvexHullRatio-0.75) > 1e-9 {
+		t.Errorf("unexpected convex hull ratio of L shape: %v", *lShape.ConvexHullRatio)
+	}
+
+	empty := getAdmCompactness(0, 0, 0)
+	if empty.PolsbyPopper != nil || empty.ConvexHullRatio != nil {
+		t.Errorf("expected no compactness of empty geometry, got %+v", empty)
+	}
+}
@@ -0,0 +1,81 @@
+package adm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const (
+	streamStatusComplete = "complete"
+	streamStatusError    = "error"
+)
+
+const (
+	trailerStreamStatus = "X-Stream-Status"
+	trailerFeaturesSent = "X-Features-Sent"
+	trailerResumeCursor = "X-Resume-Cursor"
+)
+
+// Summary of a streamed response. It is sent as HTTP trailers and, when
+// requested, as the last NDJSON record, so that a truncated stream is never
+// mistaken for a complete one and can be resumed from the cursor.
+type streamTrailer struct {
+	Type         string `json:"type"`
+	Status       string `json:"status"`
+	FeaturesSent int64  `json:"features_sent"`
+	// start-after-id, or start-after-fid when paginating by fid, of next request
+	ResumeCursor string `json:"resume_cursor,omitempty"`
+	Next         string `json:"next,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func newStreamTrailer(
+	baseUrl url.URL,
+	opts admQueryOpts,
+	lastFeature *geojson.Feature,
+	featuresSent int64,
+	streamErr error,
+) streamTrailer {
+	trailer := streamTrailer{
+		Type:         "trailer",
+		Status:       streamStatusComplete,
+		FeaturesSent: featuresSent,
+	}
+	if streamErr == nil {
+		return trailer
+	}
+
+	trailer.Status = streamStatusError
+	trailer.Error = "stream_failed"
+	// without any feature sent the original request is simply repeated
+	if lastFeature == nil {
+		return trailer
+	}
+
+	if opts.startAfterFid != nil {
+		trailer.ResumeCursor = fmt.Sprint(lastFeature.Properties["fid"])
+	} else {
+		trailer.ResumeCursor = fmt.Sprint(lastFeature.ID)
+	}
+	trailer.Next = getAdmsNextUrl(baseUrl, lastFeature, opts)
+	return trailer
+}
+
+// Announces trailers, must be called before the response header is written.
+func declareStreamTrailers(w http.ResponseWriter) {
+	w.Header().Set("Trailer", strings.Join(
+		[]string{trailerStreamStatus, trailerFeaturesSent, trailerResumeCursor}, ", "))
+}
+
+func (trailer streamTrailer) setHttpTrailers(w http.ResponseWriter) {
+	w.Header().Set(trailerStreamStatus, trailer.Status)
+	w.Header().Set(trailerFeaturesSent, strconv.FormatInt(trailer.FeaturesSent, 10))
+	if trailer.ResumeCursor != "" {
+		w.Header().Set(trailerResumeCursor, trailer.ResumeCursor)
+	}
+}
@@ -0,0 +1,83 @@
+package adm
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestNewStreamTrailer(t *testing.T) {
+	t.Logf("Test: newStreamTrailer - status and resume cursor")
+
+	baseUrl := url.URL{Path: "/api/v1/geojsonl"}
+	lv := 5
+	opts, _ := NewAdmQueryOptsBuilder().
+		SetLvAndBatchSize(&lv, nil).
+		SetProjection(admProjection{geometry: admGeometryFull}).
+		Build()
+	lastFeature := &geojson.Feature{ID: "b3c1", Properties: map[string]interface{}{"fid": "4012"}}
+	streamErr := errors.New("connection_reset")
+
+	trailer := newStreamTrailer(baseUrl, opts, lastFeature, 10, nil)
+	if trailer.Status != streamStatusComplete || trailer.ResumeCursor != "" || trailer.Next != "" {
+		t.Errorf("unexpected trailer of complete stream: %+v", trailer)
+	}
+
+	trailer = newStreamTrailer(baseUrl, opts, lastFeature, 10, streamErr)
+	if trailer.Status != streamStatusError || trailer.FeaturesSent != 10 || trailer.ResumeCursor != "b3c1" {
+		t.Errorf("unexpected trailer of failed stream: %+v", trailer)
+	}
+	expectedNext := "/api/v1/geojsonl?lv=5&start-after-id=b3c1"
+	if trailer.Next != expectedNext {
+		t.Errorf("unexpected next url: %s. Expected %s", trailer.Next, expectedNext)
+	}
+
+	startAfterFid := "1000"
+	opts.startAfterFid = &startAfterFid
+	trailer = newStreamTrailer(baseUrl, opts, lastFeature, 10, streamErr)
+	if trailer.ResumeCursor != "4012" {
+		t.Errorf("expected fid resume cursor, got %s", trailer.ResumeCursor)
+	}
+
+	trailer = newStreamTrailer(baseUrl, opts, nil, 0, streamErr)
+	if trailer.Status != streamStatusError || trailer.ResumeCursor != "" {
+		t.Errorf("unexpected trailer of stream failed before first feature: %+v", trailer)
+	}
+}
+
+func TestStreamTrailerHttpTrailers(t *testing.T) {
+	t.Logf("Test: streamTrailer.setHttpTrailers - trailers are sent after body")
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		declareStreamTrailers(w)
+		w.Write([]byte("{}\n"))
+		streamTrailer{Status: streamStatusError, FeaturesSent: 1, ResumeCursor: "b3c1"}.setHttpTrailers(w)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer response.Body.Close()
+	if _, err := io.ReadAll(response.Body); err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+
+	expected := map[string]string{
+		trailerStreamStatus: streamStatusError,
+		trailerFeaturesSent: "1",
+		trailerResumeCursor: "b3c1",
+	}
+	for name, value := range expected {
+		if response.Trailer.Get(name) != value {
+			t.Errorf("unexpected trailer %s: %s. Expected %s", name, response.Trailer.Get(name), value)
+		}
+	}
+}
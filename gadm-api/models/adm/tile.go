@@ -0,0 +1,110 @@
+package adm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const MAX_TILE_ZOOM = 22
+const MVT_EXTENT = 4096
+const MVT_BUFFER = 64
+
+type tileZoomRange struct {
+	minZoom int
+	maxZoom int
+}
+
+// Zoom range in which each GADM level is included in vector tiles, indexed
+// by level.
+type TileLevelZoomRanges [6]*tileZoomRange
+
+var DefaultTileLevelZoomRanges = TileLevelZoomRanges{
+	{minZoom: 0, maxZoom: MAX_TILE_ZOOM},
+	{minZoom: 3, maxZoom: MAX_TILE_ZOOM},
+	{minZoom: 5, maxZoom: MAX_TILE_ZOOM},
+	{minZoom: 7, maxZoom: MAX_TILE_ZOOM},
+	{minZoom: 9, maxZoom: MAX_TILE_ZOOM},
+	{minZoom: 10, maxZoom: MAX_TILE_ZOOM},
+}
+
+// Parses ranges in format "<lv>:<min_zoom>-<max_zoom>,..." e.g. "0:0-6,1:4-10".
+// Levels missing from the value are excluded from tiles.
+func ParseTileLevelZoomRanges(value string) (TileLevelZoomRanges, error) {
+	var ranges TileLevelZoomRanges
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		lvString, zoomRangeString, ok := strings.Cut(entry, ":")
+		if !ok {
+			return ranges, fmt.Errorf("invalid_tile_zoom_range_entry: %s", entry)
+		}
+		lv, err := getLevelIntFromString(lvString)
+		if err != nil || lv == nil {
+			return ranges, fmt.Errorf("invalid_tile_zoom_range_level: %s", entry)
+		}
+
+		minZoomString, maxZoomString, ok := strings.Cut(zoomRangeString, "-")
+		if !ok {
+			return ranges, fmt.Errorf("invalid_tile_zoom_range: %s", entry)
+		}
+		minZoom, err := strconv.Atoi(minZoomString)
+		if err != nil {
+			return ranges, fmt.Errorf("invalid_tile_min_zoom: %s: %w", entry, err)
+		}
+		maxZoom, err := strconv.Atoi(maxZoomString)
+		if err != nil {
+			return ranges, fmt.Errorf("invalid_tile_max_zoom: %s: %w", entry, err)
+		}
+		if minZoom < 0 || maxZoom > MAX_TILE_ZOOM || minZoom > maxZoom {
+			return ranges, fmt.Errorf("tile_zoom_range_error: %s", entry)
+		}
+
+		ranges[*lv] = &tileZoomRange{minZoom: minZoom, maxZoom: maxZoom}
+	}
+	return ranges, nil
+}
+
+func (ranges TileLevelZoomRanges) levelsForZoom(z int) []int {
+	levels := []int{}
+	for lv, zoomRange := range ranges {
+		if zoomRange != nil && z >= zoomRange.minZoom && z <= zoomRange.maxZoom {
+			levels = append(levels, lv)
+		}
+	}
+	return levels
+}
+
+type tileCoords struct {
+	z int
+	x int
+	y int
+}
+
+func getTileCoordsFromStrings(z string, x string, y string) (tileCoords, error) {
+	y = strings.TrimSuffix(y, ".mvt")
+	_z, err := strconv.Atoi(z)
+	if err != nil {
+		return tileCoords{}, fmt.Errorf("invalid_tile_z: %w", err)
+	}
+	_x, err := strconv.Atoi(x)
+	if err != nil {
+		return tileCoords{}, fmt.Errorf("invalid_tile_x: %w", err)
+	}
+	_y, err := strconv.Atoi(y)
+	if err != nil {
+		return tileCoords{}, fmt.Errorf("invalid_tile_y: %w", err)
+	}
+
+	if _z < 0 || _z > MAX_TILE_ZOOM {
+		return tileCoords{}, fmt.Errorf("tile_z_range_error: z=%d", _z)
+	}
+	maxXY := 1 << _z
+	if _x < 0 || _x >= maxXY || _y < 0 || _y >= maxXY {
+		return tileCoords{}, fmt.Errorf("tile_xy_range_error: z=%d x=%d y=%d", _z, _x, _y)
+	}
+	return tileCoords{z: _z, x: _x, y: _y}, nil
+}
@@ -0,0 +1,86 @@
+package adm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTileLevelZoomRanges(t *testing.T) {
+	t.Logf("Test: ParseTileLevelZoomRanges - levels, missing levels and invalid entries")
+
+	ranges, err := ParseTileLevelZoomRanges("0:0-6, 1:4-10,3:8-22,")
+	if err != nil {
+		t.Fatalf("failed to parse zoom ranges: %v", err)
+	}
+	expected := TileLevelZoomRanges{
+		{minZoom: 0, maxZoom: 6},
+		{minZoom: 4, maxZoom: 10},
+		nil,
+		{minZoom: 8, maxZoom: 22},
+		nil,
+		nil,
+	}
+	for lv := range expected {
+		if (ranges[lv] == nil) != (expected[lv] == nil) || (ranges[lv] != nil && *ranges[lv] != *expected[lv]) {
+			t.Errorf("unexpected zoom range of level %d: %+v. Expected %+v", lv, ranges[lv], expected[lv])
+		}
+	}
+
+	for _, value := range []string{
+		"0",
+		"6:0-10",
+		"x:0-10",
+		"0:5",
+		"0:a-10",
+		"0:0-b",
+		"0:-1-10",
+		"0:0-23",
+		"0:10-5",
+	} {
+		if _, err := ParseTileLevelZoomRanges(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestTileLevelZoomRangesLevelsForZoom(t *testing.T) {
+	t.Logf("Test: levelsForZoom - levels included at zoom")
+
+	cases := map[int][]int{
+		0:  {0},
+		4:  {0, 1},
+		9:  {0, 1, 2, 3, 4},
+		22: {0, 1, 2, 3, 4, 5},
+	}
+	for z, expected := range cases {
+		if levels := DefaultTileLevelZoomRanges.levelsForZoom(z); !slices.Equal(levels, expected) {
+			t.Errorf("unexpected levels for zoom %d: %v. Expected %v", z, levels, expected)
+		}
+	}
+
+	ranges, _ := ParseTileLevelZoomRanges("2:3-5")
+	if levels := ranges.levelsForZoom(6); len(levels) != 0 {
+		t.Errorf("expected no levels outside of zoom range, got %v", levels)
+	}
+}
+
+func TestGetTileCoordsFromStrings(t *testing.T) {
+	t.Logf("Test: getTileCoordsFromStrings - mvt suffix and ranges")
+
+	tile, err := getTileCoordsFromStrings("3", "7", "5.mvt")
+	if err != nil || tile != (tileCoords{z: 3, x: 7, y: 5}) {
+		t.Errorf("unexpected tile: %+v %v", tile, err)
+	}
+
+	for _, coords := range [][3]string{
+		{"23", "0", "0"},
+		{"-1", "0", "0"},
+		{"3", "8", "0"},
+		{"3", "0", "-1"},
+		{"z", "0", "0"},
+	} {
+		if _, err := getTileCoordsFromStrings(coords[0], coords[1], coords[2]); err == nil {
+			t.Errorf("expected error for %v", coords)
+		}
+	}
+}
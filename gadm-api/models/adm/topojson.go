@@ -0,0 +1,306 @@
+package adm
+
+import (
+	"encoding/binary"
+	"math"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// TopoJSON encoding, see https://github.com/topojson/topojson-specification
+
+const DEFAULT_TOPOJSON_QUANTIZATION = 100000
+const MIN_TOPOJSON_QUANTIZATION = 2
+const MAX_TOPOJSON_QUANTIZATION = 100000000
+
+const TOPOJSON_OBJECT_NAME = "adms"
+
+type topology struct {
+	Type      string                    `json:"type"`
+	Bbox      []float64                 `json:"bbox,omitempty"`
+	Transform *topologyTransform        `json:"transform,omitempty"`
+	Objects   map[string]topologyObject `json:"objects"`
+	Arcs      [][][2]int                `json:"arcs"`
+}
+
+type topologyTransform struct {
+	Scale     [2]float64 `json:"scale"`
+	Translate [2]float64 `json:"translate"`
+}
+
+type topologyObject struct {
+	Type       *string                `json:"type"`
+	Id         any                    `json:"id,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Arcs       any                    `json:"arcs,omitempty"`
+	Geometries []topologyObject       `json:"geometries,omitempty"`
+}
+
+type topoPoint [2]int
+
+// closed ring of quantized points, first point equals last
+type topoRing []topoPoint
+
+type topoPointNeighbors struct {
+	prev topoPoint
+	next topoPoint
+}
+
+type topologyBuilder struct {
+	transform topologyTransform
+	// point where rings diverge, every arc starts and ends in one
+	junctions map[topoPoint]bool
+	arcs      [][]topoPoint
+	arcIdxs   map[string]int
+}
+
+// Builds topology out of polygonal features. Neighboring GADM adms share
+// exact vertices along their common border, so after quantization the
+// border is found as identical (reversed) arc in both rings and stored once.
+func convertFeaturesToTopology(features []*geojson.Feature, quantization int) (*topology, error) {
+	// minx, miny, maxx, maxy
+	extent := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, feature := range features {
+		for _, polygon := range getFeaturePolygons(feature) {
+			for _, ring := range polygon {
+				for _, coords := range ring {
+					extent[0] = math.Min(extent[0], coords[0])
+					extent[1] = math.Min(extent[1], coords[1])
+					extent[2] = math.Max(extent[2], coords[0])
+					extent[3] = math.Max(extent[3], coords[1])
+				}
+			}
+		}
+	}
+	isEmpty := extent[0] > extent[2]
+
+	builder := &topologyBuilder{
+		junctions: map[topoPoint]bool{},
+		arcIdxs:   map[string]int{},
+	}
+	builder.transform.Scale = [2]float64{1, 1}
+	if !isEmpty {
+		builder.transform.Translate = [2]float64{extent[0], extent[1]}
+		if width := extent[2] - extent[0]; width > 0 {
+			builder.transform.Scale[0] = width / float64(quantization-1)
+		}
+		if height := extent[3] - extent[1]; height > 0 {
+			builder.transform.Scale[1] = height / float64(quantization-1)
+		}
+	}
+
+	quantized := make([][][]topoRing, len(features))
+	for i, feature := range features {
+		quantized[i] = builder.quantizePolygons(getFeaturePolygons(feature))
+	}
+
+	builder.findJunctions(quantized)
+
+	geometries := make([]topologyObject, len(features))
+	for i, feature := range features {
+		geometries[i] = topologyObject{
+			Id:         feature.ID,
+			Properties: feature.Properties,
+		}
+		if len(quantized[i]) == 0 {
+			continue
+		}
+
+		polygonArcs := make([][][]int, len(quantized[i]))
+		for j, polygon := range quantized[i] {
+			polygonArcs[j] = make([][]int, len(polygon))
+			for k, ring := range polygon {
+				polygonArcs[j][k] = builder.getRingArcs(ring)
+			}
+		}
+
+		geometryType := string(geojson.GeometryMultiPolygon)
+		geometries[i].Arcs = polygonArcs
+		if feature.Geometry.Type == geojson.GeometryPolygon {
+			geometryType = string(geojson.GeometryPolygon)
+			geometries[i].Arcs = polygonArcs[0]
+		}
+		geometries[i].Type = &geometryType
+	}
+
+	collectionType := "GeometryCollection"
+	result := &topology{
+		Type:      "Topology",
+		Transform: &builder.transform,
+		Objects: map[string]topologyObject{
+			TOPOJSON_OBJECT_NAME: {Type: &collectionType, Geometries: geometries},
+		},
+		Arcs: builder.getDeltaEncodedArcs(),
+	}
+	if !isEmpty {
+		result.Bbox = extent[:]
+	}
+	return result, nil
+}
+
+func getFeaturePolygons(feature *geojson.Feature) [][][][]float64 {
+	if feature.Geometry == nil {
+		return nil
+	}
+	switch feature.Geometry.Type {
+	case geojson.GeometryPolygon:
+		return [][][][]float64{feature.Geometry.Polygon}
+	case geojson.GeometryMultiPolygon:
+		return feature.Geometry.MultiPolygon
+	default:
+		return nil
+	}
+}
+
+// Rings collapsing to less than 3 distinct points are dropped together
+// with holes of collapsed exterior rings.
+func (builder *topologyBuilder) quantizePolygons(polygons [][][][]float64) [][]topoRing {
+	result := [][]topoRing{}
+	for _, polygon := range polygons {
+		rings := []topoRing{}
+		for i, ring := range polygon {
+			quantizedRing := builder.quantizeRing(ring)
+			if len(quantizedRing) < 4 {
+				if i == 0 {
+					break
+				}
+				continue
+			}
+			rings = append(rings, quantizedRing)
+		}
+		if len(rings) > 0 {
+			result = append(result, rings)
+		}
+	}
+	return result
+}
+
+func (builder *topologyBuilder) quantizeRing(ring [][]float64) topoRing {
+	result := topoRing{}
+	for _, coords := range ring {
+		point := topoPoint{
+			int(math.Round((coords[0] - builder.transform.Translate[0]) / builder.transform.Scale[0])),
+			int(math.Round((coords[1] - builder.transform.Translate[1]) / builder.transform.Scale[1])),
+		}
+		if len(result) > 0 && result[len(result)-1] == point {
+			continue
+		}
+		result = append(result, point)
+	}
+	if len(result) > 0 && result[0] != result[len(result)-1] {
+		result = append(result, result[0])
+	}
+	return result
+}
+
+// A point is a junction when it is visited with different neighbors than
+// the first time, i.e. the rings passing through it diverge there.
+func (builder *topologyBuilder) findJunctions(polygons [][][]topoRing) {
+	neighbors := map[topoPoint]topoPointNeighbors{}
+	for _, featurePolygons := range polygons {
+		for _, polygon := range featurePolygons {
+			for _, ring := range polygon {
+				n := len(ring) - 1
+				for i := 0; i < n; i++ {
+					point := ring[i]
+					current := topoPointNeighbors{prev: ring[(i-1+n)%n], next: ring[i+1]}
+					seen, ok := neighbors[point]
+					if !ok {
+						neighbors[point] = current
+						continue
+					}
+					sameDirection := seen.prev == current.prev && seen.next == current.next
+					oppositeDirection := seen.prev == current.next && seen.next == current.prev
+					if !sameDirection && !oppositeDirection {
+						builder.junctions[point] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// Cuts ring at junctions and returns indexes of its arcs, reversed arcs
+// are referenced with one's complement of the index.
+func (builder *topologyBuilder) getRingArcs(ring topoRing) []int {
+	n := len(ring) - 1
+	start := -1
+	for i := 0; i < n; i++ {
+		if builder.junctions[ring[i]] {
+			start = i
+			break
+		}
+	}
+
+	if start == -1 {
+		// ring without junctions is stored as single arc starting at its
+		// smallest point so that the same ring is always rotated the same way
+		start = 0
+		for i := 1; i < n; i++ {
+			if ring[i][0] < ring[start][0] || (ring[i][0] == ring[start][0] && ring[i][1] < ring[start][1]) {
+				start = i
+			}
+		}
+		return []int{builder.addArc(rotateTopoRing(ring, start))}
+	}
+
+	rotated := rotateTopoRing(ring, start)
+	arcs := []int{}
+	arcStart := 0
+	for i := 1; i <= n; i++ {
+		if i == n || builder.junctions[rotated[i]] {
+			arcs = append(arcs, builder.addArc(rotated[arcStart:i+1]))
+			arcStart = i
+		}
+	}
+	return arcs
+}
+
+func rotateTopoRing(ring topoRing, start int) topoRing {
+	n := len(ring) - 1
+	rotated := make(topoRing, 0, len(ring))
+	rotated = append(rotated, ring[start:n]...)
+	rotated = append(rotated, ring[:start+1]...)
+	return rotated
+}
+
+func getTopoArcKey(points []topoPoint, reversed bool) string {
+	buf := make([]byte, 0, len(points)*16)
+	for i := range points {
+		point := points[i]
+		if reversed {
+			point = points[len(points)-1-i]
+		}
+		buf = binary.AppendVarint(buf, int64(point[0]))
+		buf = binary.AppendVarint(buf, int64(point[1]))
+	}
+	return string(buf)
+}
+
+func (builder *topologyBuilder) addArc(points []topoPoint) int {
+	if idx, ok := builder.arcIdxs[getTopoArcKey(points, false)]; ok {
+		return idx
+	}
+	if idx, ok := builder.arcIdxs[getTopoArcKey(points, true)]; ok {
+		return ^idx
+	}
+
+	idx := len(builder.arcs)
+	builder.arcs = append(builder.arcs, append([]topoPoint{}, points...))
+	builder.arcIdxs[getTopoArcKey(points, false)] = idx
+	return idx
+}
+
+// First position of arc is absolute, the rest relative to previous one.
+func (builder *topologyBuilder) getDeltaEncodedArcs() [][][2]int {
+	result := make([][][2]int, len(builder.arcs))
+	for i, arc := range builder.arcs {
+		result[i] = make([][2]int, len(arc))
+		prev := topoPoint{0, 0}
+		for j, point := range arc {
+			result[i][j] = [2]int{point[0] - prev[0], point[1] - prev[1]}
+			prev = point
+		}
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package adm
+
+import (
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestConvertFeaturesToTopology(t *testing.T) {
+	t.Logf("Test: convertFeaturesToTopology - shared border is stored once")
+
+	// two squares sharing border x=1 and an island outside of both
+	left := geojson.NewMultiPolygonFeature([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}})
+	left.ID = "left"
+	right := geojson.NewMultiPolygonFeature([][][]float64{{{1, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 0}}})
+	right.ID = "right"
+	island := geojson.NewPolygonFeature([][][]float64{{{3, 3}, {4, 3}, {4, 4}, {3, 3}}})
+	island.ID = "island"
+
+	result, err := convertFeaturesToTopology([]*geojson.Feature{left, right, island}, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// shared border, rest of left ring, rest of right ring and island
+	if len(result.Arcs) != 4 {
+		t.Errorf("unexpected number of arcs: %d. Expected 4", len(result.Arcs))
+	}
+
+	geometries := result.Objects[TOPOJSON_OBJECT_NAME].Geometries
+	leftArcs := geometries[0].Arcs.([][][]int)[0][0]
+	rightArcs := geometries[1].Arcs.([][][]int)[0][0]
+	shared := map[int]bool{}
+	for _, arc := range leftArcs {
+		shared[arc] = true
+	}
+	foundReversed := false
+	for _, arc := range rightArcs {
+		if shared[^arc] {
+			foundReversed = true
+		}
+	}
+	if !foundReversed {
+		t.Errorf("shared border not referenced reversed: left=%v right=%v", leftArcs, rightArcs)
+	}
+
+	if *geometries[2].Type != string(geojson.GeometryPolygon) {
+		t.Errorf("unexpected island geometry type: %s", *geometries[2].Type)
+	}
+	if islandArcs := geometries[2].Arcs.([][]int); len(islandArcs) != 1 || len(islandArcs[0]) != 1 {
+		t.Errorf("unexpected island arcs: %v", islandArcs)
+	}
+
+	if result.Transform.Scale != [2]float64{1, 1} || result.Transform.Translate != [2]float64{0, 0} {
+		t.Errorf("unexpected transform: %+v", *result.Transform)
+	}
+}
@@ -0,0 +1,156 @@
+package export_job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+
+	"gadm-api/logger"
+	"gadm-api/models/adm"
+
+	"github.com/google/uuid"
+)
+
+var exportContentTypes = map[string]string{
+	adm.ExportFormatGeojsonl:   "application/geo+json-seq",
+	adm.ExportFormatCsv:        adm.CSV_CONTENT_TYPE,
+	adm.ExportFormatFlatgeobuf: "application/vnd.flatgeobuf",
+	adm.ExportFormatGeoparquet: "application/vnd.apache.parquet",
+}
+
+type exportJobHandler struct {
+	service *exportJobService
+}
+
+func NewExportJobHandler(service *exportJobService) *exportJobHandler {
+	return &exportJobHandler{service: service}
+}
+
+type createExportJobRequest struct {
+	Format string           `json:"format"`
+	Filter adm.ExportFilter `json:"filter"`
+}
+
+type exportJobResponse struct {
+	*exportJob
+	// share of processed adms in [0, 1], null until total is known
+	Progress    *float64 `json:"progress"`
+	DownloadUrl string   `json:"download_url,omitempty"`
+}
+
+func newExportJobResponse(job *exportJob, baseApiPath string) exportJobResponse {
+	response := exportJobResponse{exportJob: job}
+	if job.TotalCount != nil {
+		progress := 1.0
+		if *job.TotalCount > 0 {
+			progress = min(float64(job.ProcessedCount)/float64(*job.TotalCount), 1)
+		}
+		response.Progress = &progress
+	}
+	if job.Status == exportJobStatusSucceeded {
+		response.DownloadUrl = path.Join(baseApiPath, "exports", job.Id, "download")
+	}
+	return response
+}
+
+func (handler *exportJobHandler) CreateExportJobHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	var request createExportJobRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		logger.Error("failed_to_decode_export_job_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	job, err := handler.service.createExportJob(r.Context(), request.Format, request.Filter)
+	if errors.Is(err, errInvalidExportJob) {
+		logger.Error("invalid_export_job_request %v", err)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.Error("failed_to_create_export_job %v", err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", path.Join(baseApiPath, "exports", job.Id))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newExportJobResponse(job, baseApiPath))
+}
+
+func (handler *exportJobHandler) GetExportJobHandler(w http.ResponseWriter, r *http.Request, baseApiPath string) {
+	job, ok := handler.getExportJobFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(newExportJobResponse(job, baseApiPath))
+}
+
+func (handler *exportJobHandler) DownloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := handler.getExportJobFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	filePath, err := handler.service.getExportFilePath(job)
+	if err != nil {
+		logger.Error("export_not_ready id=%s status=%s", job.Id, job.Status)
+		http.Error(w, "export_not_ready", http.StatusConflict)
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Error("export_file_not_found id=%s", job.Id)
+		http.Error(w, "export_expired", http.StatusGone)
+		return
+	}
+	if err != nil {
+		logger.Error("failed_to_open_export_file id=%s %v", job.Id, err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		logger.Error("failed_to_stat_export_file id=%s %v", job.Id, err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return
+	}
+
+	fileName := fmt.Sprintf("adm_export_%s.%s", job.Id, job.Format)
+	w.Header().Set("Content-Type", exportContentTypes[job.Format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	http.ServeContent(w, r, fileName, info.ModTime(), file)
+}
+
+func (handler *exportJobHandler) getExportJobFromRequest(w http.ResponseWriter, r *http.Request) (*exportJob, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		logger.Error("invalid_export_job_id id=%s", id)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return nil, false
+	}
+
+	job, err := handler.service.getExportJob(r.Context(), id)
+	if errors.Is(err, errExportJobNotFound) {
+		http.Error(w, "not_found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("failed_to_get_export_job id=%s %v", id, err)
+		http.Error(w, "internal_server_error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return job, true
+}
@@ -0,0 +1,62 @@
+package export_job
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gadm-api/models/adm"
+)
+
+func TestNewExportJobResponse(t *testing.T) {
+	t.Logf("Test: newExportJobResponse - progress and download url")
+
+	total := int64(200)
+	zero := int64(0)
+	cases := []struct {
+		job              exportJob
+		expectedProgress *float64
+		expectedUrl      string
+	}{
+		{exportJob{Id: "a", Status: exportJobStatusQueued}, nil, ""},
+		{exportJob{Id: "a", Status: exportJobStatusRunning, ProcessedCount: 50, TotalCount: &total}, ptr(0.25), ""},
+		{exportJob{Id: "a", Status: exportJobStatusSucceeded, TotalCount: &zero}, ptr(1.0), "/api/v1/exports/a/download"},
+		{exportJob{Id: "a", Status: exportJobStatusFailed, ProcessedCount: 10, TotalCount: &total}, ptr(0.05), ""},
+	}
+	for _, c := range cases {
+		response := newExportJobResponse(&c.job, "/api/v1")
+		if (response.Progress == nil) != (c.expectedProgress == nil) ||
+			(response.Progress != nil && *response.Progress != *c.expectedProgress) {
+			t.Errorf("unexpected progress for %s: %v. Expected %v", c.job.Status, response.Progress, c.expectedProgress)
+		}
+		if response.DownloadUrl != c.expectedUrl {
+			t.Errorf("unexpected download url for %s: %s. Expected %s", c.job.Status, response.DownloadUrl, c.expectedUrl)
+		}
+	}
+}
+
+func TestCreateExportJobValidation(t *testing.T) {
+	t.Logf("Test: exportJobService.createExportJob - rejects invalid format and filter")
+
+	service := NewExportJobService(nil, nil, ExportJobConfig{})
+	lv := 7
+	cases := []struct {
+		format string
+		filter adm.ExportFilter
+	}{
+		{"shp", adm.ExportFilter{}},
+		{adm.ExportFormatCsv, adm.ExportFilter{Lv: &lv}},
+		{adm.ExportFormatCsv, adm.ExportFilter{Bbox: &[4]float64{0, 10, 5, 5}}},
+		{adm.ExportFormatCsv, adm.ExportFilter{Bbox: &[4]float64{-200, 0, 5, 5}}},
+	}
+	for _, c := range cases {
+		_, err := service.createExportJob(context.Background(), c.format, c.filter)
+		if !errors.Is(err, errInvalidExportJob) {
+			t.Errorf("expected invalid export job for %s %+v, got %v", c.format, c.filter, err)
+		}
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
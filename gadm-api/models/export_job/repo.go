@@ -0,0 +1,168 @@
+package export_job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gadm-api/models/adm"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	exportJobStatusQueued    = "queued"
+	exportJobStatusRunning   = "running"
+	exportJobStatusSucceeded = "succeeded"
+	exportJobStatusFailed    = "failed"
+)
+
+type exportJob struct {
+	Id             string           `db:"id" json:"id"`
+	Status         string           `db:"status" json:"status"`
+	Format         string           `db:"format" json:"format"`
+	Filter         adm.ExportFilter `db:"filter" json:"filter"`
+	ProcessedCount int64            `db:"processed_count" json:"processed_count"`
+	TotalCount     *int64           `db:"total_count" json:"total_count"`
+	FileName       *string          `db:"file_name" json:"-"`
+	Error          *string          `db:"error" json:"error,omitempty"`
+	Attempts       int              `db:"attempts" json:"-"`
+	CreatedAt      time.Time        `db:"created_at" json:"created_at"`
+	StartedAt      *time.Time       `db:"started_at" json:"started_at"`
+	FinishedAt     *time.Time       `db:"finished_at" json:"finished_at"`
+	ExpiresAt      *time.Time       `db:"expires_at" json:"expires_at"`
+}
+
+var errExportJobNotFound = errors.New("export_job_not_found")
+
+type exportJobRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewExportJobRepo(db *pgxpool.Pool) *exportJobRepo {
+	return &exportJobRepo{db}
+}
+
+func (repo *exportJobRepo) createExportJob(ctx context.Context, format string, filter adm.ExportFilter) (*exportJob, error) {
+	filterJson, err := json.Marshal(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_marshal_export_filter: %w", err)
+	}
+
+	sql, args, err := getInsertExportJobSqlQuery(format, filterJson)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_create_export_job_sql_query: %w", err)
+	}
+	return repo.queryExportJob(ctx, sql, args)
+}
+
+func (repo *exportJobRepo) getExportJob(ctx context.Context, id string) (*exportJob, error) {
+	sql, args, err := getSelectExportJobSqlQuery(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_get_export_job_sql_query: %w", err)
+	}
+	return repo.queryExportJob(ctx, sql, args)
+}
+
+// Returns errExportJobNotFound when no job is queued.
+func (repo *exportJobRepo) claimExportJob(ctx context.Context) (*exportJob, error) {
+	sql, args, err := getClaimExportJobSqlQuery()
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_claim_export_job_sql_query: %w", err)
+	}
+	return repo.queryExportJob(ctx, sql, args)
+}
+
+func (repo *exportJobRepo) queryExportJob(ctx context.Context, sql string, args []interface{}) (*exportJob, error) {
+	rows, err := repo.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_query_export_job: %w", err)
+	}
+
+	job, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[exportJob])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errExportJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_scan_export_job: %w", err)
+	}
+	return &job, nil
+}
+
+func (repo *exportJobRepo) updateExportJobProgress(
+	ctx context.Context,
+	id string,
+	processedCount int64,
+	totalCount *int64,
+) error {
+	sql, args, err := getUpdateExportJobProgressSqlQuery(id, processedCount, totalCount)
+	if err != nil {
+		return fmt.Errorf("failed_to_update_export_job_progress_sql_query: %w", err)
+	}
+	if _, err := repo.db.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed_to_update_export_job_progress: %w", err)
+	}
+	return nil
+}
+
+func (repo *exportJobRepo) finishExportJob(
+	ctx context.Context,
+	id string,
+	status string,
+	fileName *string,
+	errorMessage *string,
+	retention time.Duration,
+) error {
+	sql, args, err := getFinishExportJobSqlQuery(id, status, fileName, errorMessage, retention)
+	if err != nil {
+		return fmt.Errorf("failed_to_finish_export_job_sql_query: %w", err)
+	}
+	if _, err := repo.db.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed_to_finish_export_job: %w", err)
+	}
+	return nil
+}
+
+func (repo *exportJobRepo) requeueStaleExportJobs(
+	ctx context.Context,
+	staleAfter time.Duration,
+	maxAttempts int,
+	retention time.Duration,
+) (int64, error) {
+	sql, args, err := getRequeueStaleExportJobsSqlQuery(staleAfter, maxAttempts, retention)
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_requeue_stale_export_jobs_sql_query: %w", err)
+	}
+	result, err := repo.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed_to_requeue_stale_export_jobs: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// Deletes expired jobs and returns file names of their artifacts.
+func (repo *exportJobRepo) deleteExpiredExportJobs(ctx context.Context) ([]string, error) {
+	sql, args, err := getDeleteExpiredExportJobsSqlQuery()
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_delete_expired_export_jobs_sql_query: %w", err)
+	}
+	rows, err := repo.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_delete_expired_export_jobs: %w", err)
+	}
+	fileNames, err := pgx.CollectRows(rows, pgx.RowTo[*string])
+	if err != nil {
+		return nil, fmt.Errorf("failed_to_scan_expired_export_jobs: %w", err)
+	}
+
+	result := []string{}
+	for _, fileName := range fileNames {
+		if fileName != nil {
+			result = append(result, *fileName)
+		}
+	}
+	return result, nil
+}
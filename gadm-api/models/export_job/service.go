@@ -0,0 +1,231 @@
+package export_job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gadm-api/logger"
+	"gadm-api/models/adm"
+	"gadm-api/utils"
+)
+
+const DEFAULT_EXPORT_WORKERS = 2
+const DEFAULT_EXPORT_RETENTION = 24 * time.Hour
+
+const EXPORT_JOB_POLL_INTERVAL = 5 * time.Second
+const EXPORT_JOB_HEARTBEAT_INTERVAL = 10 * time.Second
+const EXPORT_JOB_CLEANUP_INTERVAL = time.Minute
+
+// Running job without heartbeat for this long is considered abandoned.
+const EXPORT_JOB_STALE_AFTER = time.Minute
+const EXPORT_JOB_MAX_ATTEMPTS = 3
+
+var errInvalidExportJob = errors.New("invalid_export_job")
+
+type ExportJobConfig struct {
+	// directory of export artifacts, shared by all api instances
+	OutputDir string
+	// number of exports run concurrently, 0 disables workers
+	Workers   int
+	Retention time.Duration
+}
+
+type exportJobService struct {
+	repo       *exportJobRepo
+	admService *adm.Service
+	config     ExportJobConfig
+}
+
+func NewExportJobService(repo *exportJobRepo, admService *adm.Service, config ExportJobConfig) *exportJobService {
+	return &exportJobService{repo: repo, admService: admService, config: config}
+}
+
+func (service *exportJobService) createExportJob(
+	ctx context.Context,
+	format string,
+	filter adm.ExportFilter,
+) (*exportJob, error) {
+	if !slices.Contains(adm.ExportFormats, format) {
+		return nil, fmt.Errorf("%w: format=%s", errInvalidExportJob, format)
+	}
+	filter.Country = strings.ToUpper(filter.Country)
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidExportJob, err)
+	}
+	return service.repo.createExportJob(ctx, format, filter)
+}
+
+func (service *exportJobService) getExportJob(ctx context.Context, id string) (*exportJob, error) {
+	return service.repo.getExportJob(ctx, id)
+}
+
+func (service *exportJobService) getExportFilePath(job *exportJob) (string, error) {
+	if job.Status != exportJobStatusSucceeded || job.FileName == nil {
+		return "", errExportJobNotFound
+	}
+	return filepath.Join(service.config.OutputDir, *job.FileName), nil
+}
+
+// Starts workers and cleanup of expired exports, both stop with ctx. Jobs
+// are persisted so that queued ones survive restarts and running ones are
+// queued again once their heartbeat goes stale.
+func (service *exportJobService) StartWorkers(ctx context.Context) error {
+	if service.config.Workers <= 0 {
+		logger.Info("export_job_workers_disabled")
+		return nil
+	}
+	if err := os.MkdirAll(service.config.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed_to_create_export_dir: %w", err)
+	}
+
+	for i := 0; i < service.config.Workers; i++ {
+		go service.runWorker(ctx)
+	}
+	go service.runCleanup(ctx)
+	logger.Info("export_job_workers_started workers=%d output_dir=%s", service.config.Workers, service.config.OutputDir)
+	return nil
+}
+
+func (service *exportJobService) runWorker(ctx context.Context) {
+	for {
+		job, err := service.repo.claimExportJob(ctx)
+		if err != nil {
+			if !errors.Is(err, errExportJobNotFound) {
+				logger.Error("failed_to_claim_export_job %v", err)
+			}
+			if utils.Sleep(ctx, EXPORT_JOB_POLL_INTERVAL) != nil {
+				return
+			}
+			continue
+		}
+		service.runExportJob(ctx, job)
+	}
+}
+
+func (service *exportJobService) runExportJob(ctx context.Context, job *exportJob) {
+	logger.Info("export_job_started id=%s format=%s attempt=%d", job.Id, job.Format, job.Attempts)
+
+	totalCount, err := service.admService.CountAdmsForExport(ctx, job.Filter)
+	if err != nil {
+		service.failExportJob(ctx, job, err)
+		return
+	}
+	if err := service.repo.updateExportJobProgress(ctx, job.Id, 0, &totalCount); err != nil {
+		logger.Error("failed_to_update_export_job_progress id=%s %v", job.Id, err)
+	}
+
+	// progress doubles as heartbeat, so it is written even when unchanged
+	var processedCount atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(EXPORT_JOB_HEARTBEAT_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := service.repo.updateExportJobProgress(ctx, job.Id, processedCount.Load(), nil)
+				if err != nil {
+					logger.Error("failed_to_update_export_job_progress id=%s %v", job.Id, err)
+				}
+			}
+		}
+	}()
+
+	fileName := fmt.Sprintf("%s.%s", job.Id, job.Format)
+	err = utils.WriteFileAtomically(
+		filepath.Join(service.config.OutputDir, fileName),
+		func(w io.Writer) error {
+			return service.admService.WriteAdmsExport(ctx, w, job.Format, job.Filter, processedCount.Store)
+		},
+	)
+	close(done)
+	if ctx.Err() != nil {
+		// stopping, job is queued again once its heartbeat goes stale
+		return
+	}
+	if err != nil {
+		service.failExportJob(ctx, job, err)
+		return
+	}
+
+	if err := service.repo.updateExportJobProgress(ctx, job.Id, processedCount.Load(), nil); err != nil {
+		logger.Error("failed_to_update_export_job_progress id=%s %v", job.Id, err)
+	}
+	err = service.repo.finishExportJob(
+		ctx, job.Id, exportJobStatusSucceeded, &fileName, nil, service.config.Retention)
+	if err != nil {
+		logger.Error("failed_to_finish_export_job id=%s %v", job.Id, err)
+		return
+	}
+	logger.Info("export_job_succeeded id=%s count=%d", job.Id, processedCount.Load())
+}
+
+func (service *exportJobService) failExportJob(ctx context.Context, job *exportJob, jobErr error) {
+	logger.Error("export_job_failed id=%s %v", job.Id, jobErr)
+	// details of internal errors are kept in logs
+	errorMessage := "export_failed"
+	if errors.Is(jobErr, adm.ErrInvalidExportFilter) {
+		errorMessage = "invalid_export_filter"
+	}
+	err := service.repo.finishExportJob(
+		ctx, job.Id, exportJobStatusFailed, nil, &errorMessage, service.config.Retention)
+	if err != nil {
+		logger.Error("failed_to_finish_export_job id=%s %v", job.Id, err)
+	}
+}
+
+func (service *exportJobService) runCleanup(ctx context.Context) {
+	for {
+		service.cleanup(ctx)
+		if utils.Sleep(ctx, EXPORT_JOB_CLEANUP_INTERVAL) != nil {
+			return
+		}
+	}
+}
+
+func (service *exportJobService) cleanup(ctx context.Context) {
+	requeued, err := service.repo.requeueStaleExportJobs(
+		ctx, EXPORT_JOB_STALE_AFTER, EXPORT_JOB_MAX_ATTEMPTS, service.config.Retention)
+	if err != nil {
+		logger.Error("failed_to_requeue_stale_export_jobs %v", err)
+	} else if requeued > 0 {
+		logger.Warning("export_jobs_requeued count=%d", requeued)
+	}
+
+	fileNames, err := service.repo.deleteExpiredExportJobs(ctx)
+	if err != nil {
+		logger.Error("failed_to_delete_expired_export_jobs %v", err)
+	}
+	for _, fileName := range fileNames {
+		err := os.Remove(filepath.Join(service.config.OutputDir, fileName))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Error("failed_to_remove_export_file %s %v", fileName, err)
+		}
+	}
+
+	// temporary files left behind by stopped workers
+	tmpFiles, err := filepath.Glob(filepath.Join(service.config.OutputDir, ".*.tmp"))
+	if err != nil {
+		logger.Error("failed_to_list_export_tmp_files %v", err)
+		return
+	}
+	for _, tmpFile := range tmpFiles {
+		info, err := os.Stat(tmpFile)
+		if err != nil || time.Since(info.ModTime()) < service.config.Retention {
+			continue
+		}
+		if err := os.Remove(tmpFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Error("failed_to_remove_export_tmp_file %s %v", tmpFile, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package export_job
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var psql = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+
+var exportJobColumns = []string{
+	"id", "status", "format", "filter", "processed_count", "total_count", "file_name", "error",
+	"attempts", "created_at", "started_at", "finished_at", "expires_at",
+}
+
+func getInsertExportJobSqlQuery(format string, filter []byte) (string, []interface{}, error) {
+	return psql.
+		Insert("export_jobs").
+		Columns("format", "filter").
+		Values(format, filter).
+		Suffix("RETURNING " + strings.Join(exportJobColumns, ", ")).
+		ToSql()
+}
+
+func getSelectExportJobSqlQuery(id string) (string, []interface{}, error) {
+	return psql.
+		Select(exportJobColumns...).
+		From("export_jobs").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+}
+
+// Marks the oldest queued job as running. Locked rows are skipped so that
+// concurrent workers never claim the same job.
+func getClaimExportJobSqlQuery() (string, []interface{}, error) {
+	return psql.
+		Update("export_jobs").
+		Set("status", exportJobStatusRunning).
+		Set("started_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("heartbeat_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Set("processed_count", 0).
+		Where(`id = (
+			SELECT id FROM export_jobs
+			WHERE status = ?
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)`, exportJobStatusQueued).
+		Suffix("RETURNING " + strings.Join(exportJobColumns, ", ")).
+		ToSql()
+}
+
+func getUpdateExportJobProgressSqlQuery(id string, processedCount int64, totalCount *int64) (string, []interface{}, error) {
+	query := psql.
+		Update("export_jobs").
+		Set("processed_count", processedCount).
+		Set("heartbeat_at", squirrel.Expr("CURRENT_TIMESTAMP"))
+	if totalCount != nil {
+		query = query.Set("total_count", *totalCount)
+	}
+	return query.
+		Where(squirrel.Eq{"id": id, "status": exportJobStatusRunning}).
+		ToSql()
+}
+
+func getFinishExportJobSqlQuery(
+	id string,
+	status string,
+	fileName *string,
+	errorMessage *string,
+	retention time.Duration,
+) (string, []interface{}, error) {
+	return psql.
+		Update("export_jobs").
+		Set("status", status).
+		Set("file_name", fileName).
+		Set("error", errorMessage).
+		Set("finished_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("expires_at", squirrel.Expr("CURRENT_TIMESTAMP + make_interval(secs => ?)", retention.Seconds())).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+}
+
+// Running jobs without heartbeat were abandoned by a stopped worker, they
+// are queued again unless they used up their attempts.
+func getRequeueStaleExportJobsSqlQuery(
+	staleAfter time.Duration,
+	maxAttempts int,
+	retention time.Duration,
+) (string, []interface{}, error) {
+	return psql.
+		Update("export_jobs").
+		Set("status", squirrel.Expr("CASE WHEN attempts < ? THEN ? ELSE ? END",
+			maxAttempts, exportJobStatusQueued, exportJobStatusFailed)).
+		Set("error", squirrel.Expr("CASE WHEN attempts < ? THEN NULL ELSE ? END",
+			maxAttempts, "worker_stopped")).
+		Set("finished_at", squirrel.Expr("CASE WHEN attempts < ? THEN NULL ELSE CURRENT_TIMESTAMP END",
+			maxAttempts)).
+		Set("expires_at", squirrel.Expr(
+			"CASE WHEN attempts < ? THEN NULL ELSE CURRENT_TIMESTAMP + make_interval(secs => ?) END",
+			maxAttempts, retention.Seconds())).
+		Where(squirrel.Eq{"status": exportJobStatusRunning}).
+		Where("heartbeat_at < CURRENT_TIMESTAMP - make_interval(secs => ?)", staleAfter.Seconds()).
+		ToSql()
+}
+
+func getDeleteExpiredExportJobsSqlQuery() (string, []interface{}, error) {
+	return psql.
+		Delete("export_jobs").
+		Where("expires_at < CURRENT_TIMESTAMP").
+		Suffix("RETURNING file_name").
+		ToSql()
+}
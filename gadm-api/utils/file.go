@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Writes to a temporary file in the same directory and renames it over
+// filePath, so readers see either the previous or the complete new file.
+func WriteFileAtomically(filePath string, write func(w io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed_to_create_temp_file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	bw := bufio.NewWriterSize(tmp, 1024*1024)
+	if err = write(bw); err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("failed_to_flush_temp_file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed_to_sync_temp_file: %w", err)
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed_to_chmod_temp_file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed_to_close_temp_file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed_to_rename_temp_file: %w", err)
+	}
+	return nil
+}
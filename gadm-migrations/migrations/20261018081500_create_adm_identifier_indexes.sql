@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_adm_gid
+    ON gadm.adm ((metadata ->> ('gid_' || lv::text)));
+
+CREATE INDEX IF NOT EXISTS idx_adm_hasc
+    ON gadm.adm ((metadata ->> ('hasc_' || lv::text)))
+    WHERE lv BETWEEN 1 AND 3;
+
+CREATE INDEX IF NOT EXISTS idx_adm_iso_1
+    ON gadm.adm ((metadata ->> 'iso_1'))
+    WHERE lv = 1;
+
+-- +goose Down
+DROP INDEX IF EXISTS gadm.idx_adm_iso_1;
+DROP INDEX IF EXISTS gadm.idx_adm_hasc;
+DROP INDEX IF EXISTS gadm.idx_adm_gid;
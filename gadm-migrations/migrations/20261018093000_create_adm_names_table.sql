@@ -0,0 +1,71 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent() is only STABLE because its dictionary can change, wrapping it
+-- with an explicit dictionary allows using it in index expressions.
+CREATE OR REPLACE FUNCTION gadm.normalize_adm_name(name TEXT)
+    RETURNS TEXT
+    LANGUAGE sql
+    IMMUTABLE PARALLEL SAFE STRICT
+AS $$
+    SELECT lower(public.unaccent('public.unaccent'::regdictionary, name))
+$$;
+
+CREATE TABLE IF NOT EXISTS gadm.adm_names (
+    adm_id UUID NOT NULL REFERENCES gadm.adm(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    name_type TEXT NOT NULL,
+    normalized_name TEXT GENERATED ALWAYS AS (gadm.normalize_adm_name(name)) STORED
+);
+
+-- Rebuilds names of all adms, also run by populate_adm_names cron job after
+-- adms are loaded or updated.
+CREATE OR REPLACE FUNCTION gadm.refresh_adm_names()
+    RETURNS BIGINT
+    LANGUAGE plpgsql
+AS $$
+DECLARE
+    inserted BIGINT;
+BEGIN
+    DELETE FROM gadm.adm_names;
+
+    -- GADM keeps alternative names as a single '|' separated string
+    INSERT INTO gadm.adm_names (adm_id, name, name_type)
+    SELECT DISTINCT adm.id, trim(names.name), names.name_type
+    FROM gadm.adm
+    CROSS JOIN LATERAL (
+        SELECT COALESCE(adm.metadata ->> ('name_' || adm.lv::text), adm.metadata ->> 'country') AS name, 'name' AS name_type
+        UNION ALL
+        SELECT unnest(string_to_array(adm.metadata ->> ('varname_' || adm.lv::text), '|')), 'varname'
+        UNION ALL
+        SELECT unnest(string_to_array(adm.metadata ->> ('nl_name_' || adm.lv::text), '|')), 'nl_name'
+    ) AS names
+    WHERE names.name IS NOT NULL
+        AND trim(names.name) NOT IN ('', 'NA');
+
+    GET DIAGNOSTICS inserted = ROW_COUNT;
+    RETURN inserted;
+END;
+$$;
+
+SELECT gadm.refresh_adm_names();
+
+CREATE INDEX IF NOT EXISTS idx_adm_names_adm_id
+    ON gadm.adm_names (adm_id);
+
+CREATE INDEX IF NOT EXISTS idx_adm_names_normalized_name_trgm
+    ON gadm.adm_names USING GIN (normalized_name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_adm_gid_0
+    ON gadm.adm ((metadata ->> 'gid_0'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS gadm.idx_adm_gid_0;
+DROP FUNCTION IF EXISTS gadm.refresh_adm_names();
+DROP TABLE IF EXISTS gadm.adm_names;
+DROP FUNCTION IF EXISTS gadm.normalize_adm_name(TEXT);
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+    status TEXT NOT NULL DEFAULT 'queued',
+    format TEXT NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}'::jsonb,
+    processed_count BIGINT NOT NULL DEFAULT 0,
+    total_count BIGINT,
+    file_name TEXT,
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMPTZ,
+    heartbeat_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+-- workers pick the oldest queued job
+CREATE INDEX IF NOT EXISTS idx_export_jobs_queued
+    ON export_jobs (created_at) WHERE status = 'queued';
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_expires_at
+    ON export_jobs (expires_at) WHERE expires_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_export_jobs_expires_at;
+DROP INDEX IF EXISTS idx_export_jobs_queued;
+DROP TABLE IF EXISTS export_jobs;
+-- +goose StatementEnd